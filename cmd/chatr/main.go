@@ -1,7 +1,13 @@
 package main
 
-import "github.com/teamcutter/chatr/internal/cli"
+import (
+	"os"
+
+	"github.com/teamcutter/chatr/internal/cli"
+)
 
 func main() {
-	cli.Execute()
+	if err := cli.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,236 @@
+package bundle
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Issue is a Brewfile line that was not imported, or imported without
+// some of its options.
+type Issue struct {
+	Line    int
+	Text    string
+	Reason  string
+	Skipped bool
+	// Package is set when a skipped line asks for a package, which is
+	// then missing from the bundle
+	Package bool
+}
+
+// Taps whose packages are in the chatr indexes, or that only add
+// commands and need no mapping.
+var knownTaps = map[string]bool{
+	"homebrew/core":     true,
+	"homebrew/cask":     true,
+	"homebrew/bundle":   true,
+	"homebrew/services": true,
+}
+
+var unsupportedKinds = map[string]string{
+	"mas":       "Mac App Store apps are not supported",
+	"vscode":    "VS Code extensions are not supported",
+	"whalebrew": "whalebrew images are not supported",
+}
+
+var directive = regexp.MustCompile(`^([a-z_]+)\s*\(?\s*(.*?)\)?$`)
+
+// IsBrewfile reports whether path names a Homebrew Brewfile rather than
+// a Chatrfile.
+func IsBrewfile(path string) bool {
+	base := filepath.Base(path)
+	return base == "Brewfile" || strings.HasSuffix(base, ".Brewfile")
+}
+
+// ParseBrewfile reads the subset of the Brewfile DSL used in practice:
+// brew, cask and tap lines with trailing options. Everything else is
+// reported as an issue instead of failing the whole file.
+func ParseBrewfile(path string) (*Bundle, []Issue, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var b Bundle
+	var issues []Issue
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+
+		var kind, rest string
+		skip := func(reason string) {
+			issues = append(issues, Issue{Line: n, Text: text, Reason: reason, Skipped: true})
+		}
+		skipPackage := func(reason string) {
+			issues = append(issues, Issue{Line: n, Text: text, Reason: reason, Skipped: true, Package: kind != "tap"})
+		}
+
+		m := directive.FindStringSubmatch(text)
+		if m == nil {
+			skip("not a brew, cask or tap line")
+			continue
+		}
+		kind, rest = m[1], m[2]
+
+		if reason, ok := unsupportedKinds[kind]; ok {
+			skipPackage(reason)
+			continue
+		}
+		if kind != "brew" && kind != "cask" && kind != "tap" {
+			skip(kind + " lines are not supported")
+			continue
+		}
+
+		name, rest, ok := quoted(rest)
+		if !ok {
+			skipPackage("expected a quoted name")
+			continue
+		}
+		if hasModifier(rest) {
+			skipPackage("conditional lines are not supported")
+			continue
+		}
+
+		if kind == "tap" {
+			if !knownTaps[name] {
+				skip("third-party taps are not supported")
+			}
+			continue
+		}
+
+		// Fully qualified names only map when they come from the core taps
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			tap := name[:i]
+			if tap != "homebrew/core" && tap != "homebrew/cask" {
+				skipPackage("packages from tap " + tap + " are not supported")
+				continue
+			}
+			name = name[i+1:]
+		}
+
+		if seen[name] {
+			skip(name + " is listed more than once")
+			continue
+		}
+		seen[name] = true
+
+		if kind == "cask" {
+			b.Casks = append(b.Casks, Entry{Name: name})
+		} else {
+			b.Formulae = append(b.Formulae, Entry{Name: name})
+		}
+
+		if keys := optionKeys(rest); len(keys) > 0 {
+			issues = append(issues, Issue{
+				Line:   n,
+				Text:   text,
+				Reason: "ignoring options " + strings.Join(keys, ", "),
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &b, issues, nil
+}
+
+// stripComment removes a trailing # comment outside of quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// quoted splits a leading single or double quoted string off s.
+func quoted(s string) (value, rest string, ok bool) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return "", s, false
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", s, false
+	}
+	return s[1 : end+1], strings.TrimSpace(s[end+2:]), true
+}
+
+// hasModifier reports whether rest ends in a Ruby if or unless modifier.
+func hasModifier(rest string) bool {
+	for _, part := range splitTopLevel(rest) {
+		fields := strings.Fields(part)
+		for _, f := range fields {
+			if f == "if" || f == "unless" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// optionKeys returns the keys of trailing options such as
+// `, args: ["x"], link: false` or `, "restart_service" => true`.
+func optionKeys(rest string) []string {
+	var keys []string
+	for _, part := range splitTopLevel(strings.TrimPrefix(rest, ",")) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var key string
+		if k, _, ok := strings.Cut(part, "=>"); ok {
+			key = strings.Trim(strings.TrimSpace(k), `:"'`)
+		} else if k, _, ok := strings.Cut(part, ":"); ok {
+			key = strings.TrimSpace(k)
+		} else {
+			key = part
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// splitTopLevel splits s on commas that are outside quotes, brackets and
+// braces.
+func splitTopLevel(s string) []string {
+	var parts []string
+	var quote rune
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{' || r == '(':
+			depth++
+		case r == ']' || r == '}' || r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,168 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseBrewfile(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  string
+		want   *Bundle
+		issues []Issue
+	}{
+		{
+			name:  "brew and cask lines",
+			lines: "brew \"jq\"\ncask 'firefox'\nbrew(\"gh\")\n",
+			want: &Bundle{
+				Formulae: []Entry{{Name: "jq"}, {Name: "gh"}},
+				Casks:    []Entry{{Name: "firefox"}},
+			},
+		},
+		{
+			name:  "comments and blank lines",
+			lines: "# tools\n\nbrew \"jq\" # json\nbrew \"c#-tool\"\n",
+			want:  &Bundle{Formulae: []Entry{{Name: "jq"}, {Name: "c#-tool"}}},
+		},
+		{
+			name:  "core taps",
+			lines: "tap \"homebrew/core\"\ntap \"homebrew/bundle\"\nbrew \"homebrew/core/jq\"\ncask \"homebrew/cask/firefox\"\n",
+			want: &Bundle{
+				Formulae: []Entry{{Name: "jq"}},
+				Casks:    []Entry{{Name: "firefox"}},
+			},
+		},
+		{
+			name:  "options are dropped",
+			lines: "brew \"postgresql@16\", restart_service: :changed, link: true\n",
+			want:  &Bundle{Formulae: []Entry{{Name: "postgresql@16"}}},
+			issues: []Issue{
+				{Line: 1, Text: `brew "postgresql@16", restart_service: :changed, link: true`, Reason: "ignoring options restart_service, link"},
+			},
+		},
+		{
+			name:  "third-party taps",
+			lines: "tap \"hashicorp/tap\"\nbrew \"hashicorp/tap/terraform\"\n",
+			want:  &Bundle{},
+			issues: []Issue{
+				{Line: 1, Text: `tap "hashicorp/tap"`, Reason: "third-party taps are not supported", Skipped: true},
+				{Line: 2, Text: `brew "hashicorp/tap/terraform"`, Reason: "packages from tap hashicorp/tap are not supported", Skipped: true, Package: true},
+			},
+		},
+		{
+			name:  "unsupported lines",
+			lines: "mas \"Xcode\", id: 497799835\ncask_args appdir: \"~/Applications\"\nbrew \"gcc\" if OS.linux?\nbrew jq\n",
+			want:  &Bundle{},
+			issues: []Issue{
+				{Line: 1, Text: `mas "Xcode", id: 497799835`, Reason: "Mac App Store apps are not supported", Skipped: true, Package: true},
+				{Line: 2, Text: `cask_args appdir: "~/Applications"`, Reason: "cask_args lines are not supported", Skipped: true},
+				{Line: 3, Text: `brew "gcc" if OS.linux?`, Reason: "conditional lines are not supported", Skipped: true, Package: true},
+				{Line: 4, Text: `brew jq`, Reason: "expected a quoted name", Skipped: true, Package: true},
+			},
+		},
+		{
+			name:  "duplicates",
+			lines: "brew \"jq\"\nbrew \"homebrew/core/jq\"\n",
+			want:  &Bundle{Formulae: []Entry{{Name: "jq"}}},
+			issues: []Issue{
+				{Line: 2, Text: `brew "homebrew/core/jq"`, Reason: "jq is listed more than once", Skipped: true},
+			},
+		},
+		{
+			name:  "not a directive",
+			lines: "if OS.mac?\n\"jq\"\n",
+			want:  &Bundle{},
+			issues: []Issue{
+				{Line: 1, Text: "if OS.mac?", Reason: "if lines are not supported", Skipped: true},
+				{Line: 2, Text: `"jq"`, Reason: "not a brew, cask or tap line", Skipped: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "Brewfile")
+			if err := os.WriteFile(path, []byte(tt.lines), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, issues, err := ParseBrewfile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBrewfile() bundle = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("ParseBrewfile() issues = %+v, want %+v", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`brew "jq"`, `brew "jq"`},
+		{`brew "jq" # json`, `brew "jq" `},
+		{`# whole line`, ``},
+		{`brew "c#"`, `brew "c#"`},
+		{`brew 'c#' # tool`, `brew 'c#' `},
+		{`brew "it's" # quote`, `brew "it's" `},
+		{`brew "unterminated #`, `brew "unterminated #`},
+	}
+
+	for _, tt := range tests {
+		if got := stripComment(tt.line); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTopLevel(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{``, []string{``}},
+		{`a`, []string{`a`}},
+		{`a, b`, []string{`a`, ` b`}},
+		{`args: ["x", "y"], link: false`, []string{`args: ["x", "y"]`, ` link: false`}},
+		{`env: {A: 1, B: 2}, c`, []string{`env: {A: 1, B: 2}`, ` c`}},
+		{`f(1, 2), g`, []string{`f(1, 2)`, ` g`}},
+		{`"a, b", 'c, d'`, []string{`"a, b"`, ` 'c, d'`}},
+		{`a,`, []string{`a`, ``}},
+	}
+
+	for _, tt := range tests {
+		if got := splitTopLevel(tt.s); !slices.Equal(got, tt.want) {
+			t.Errorf("splitTopLevel(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestIsBrewfile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"Brewfile", true},
+		{"/home/me/dotfiles/Brewfile", true},
+		{"work.Brewfile", true},
+		{"Chatrfile", false},
+		{"Brewfile.lock.json", false},
+		{"brewfile", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBrewfile(tt.path); got != tt.want {
+			t.Errorf("IsBrewfile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/teamcutter/chatr/internal/tomlfile"
+)
+
+// FileName is the bundle file looked up in the current directory.
+const FileName = "Chatrfile"
+
+// Bundle lists the packages a machine should have installed.
+type Bundle struct {
+	Formulae []Entry `toml:"formulae,omitempty"`
+	Casks    []Entry `toml:"casks,omitempty"`
+}
+
+// Entry is one package in a bundle. Setting Version pins the package to
+// that version, Pin pins it to whatever version gets installed.
+type Entry struct {
+	Name    string `toml:"name"`
+	Version string `toml:"version,omitempty"`
+	Pin     bool   `toml:"pin,omitempty"`
+	SHA256  string `toml:"sha256,omitempty"`
+}
+
+// Pinned reports whether the package should be pinned once installed.
+func (e Entry) Pinned() bool {
+	return e.Pin || e.Version != ""
+}
+
+// Load reads and validates the bundle at path.
+func Load(path string) (*Bundle, error) {
+	var b Bundle
+	if err := tomlfile.Decode(path, &b); err != nil {
+		return nil, err
+	}
+
+	if err := b.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &b, nil
+}
+
+func (b *Bundle) validate() error {
+	seen := make(map[string]bool)
+	for _, list := range [][]Entry{b.Formulae, b.Casks} {
+		for _, e := range list {
+			if e.Name == "" {
+				return fmt.Errorf("entry without a name")
+			}
+			if seen[e.Name] {
+				return fmt.Errorf("%s is listed more than once", e.Name)
+			}
+			seen[e.Name] = true
+		}
+	}
+	return nil
+}
+
+// Write encodes the bundle as TOML.
+func (b *Bundle) Write(w io.Writer) error {
+	return toml.NewEncoder(w).Encode(b)
+}
+
+// Save writes the bundle to path, replacing any existing file.
+func (b *Bundle) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return b.Write(f)
+}
@@ -0,0 +1,145 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Bundle
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    &Bundle{},
+		},
+		{
+			name: "formulae and casks",
+			content: `
+[[formulae]]
+  name = "jq"
+
+[[formulae]]
+  name = "terraform"
+  version = "1.5.7"
+  sha256 = "abc"
+
+[[formulae]]
+  name = "go"
+  pin = true
+
+[[casks]]
+  name = "firefox"
+`,
+			want: &Bundle{
+				Formulae: []Entry{
+					{Name: "jq"},
+					{Name: "terraform", Version: "1.5.7", SHA256: "abc"},
+					{Name: "go", Pin: true},
+				},
+				Casks: []Entry{{Name: "firefox"}},
+			},
+		},
+		{
+			name: "unknown key",
+			content: `
+[[formulae]]
+  name = "jq"
+  versoin = "1.7"
+`,
+			wantErr: "unknown keys: formulae.versoin",
+		},
+		{
+			name:    "unknown table",
+			content: "[[brews]]\n  name = \"jq\"\n",
+			wantErr: "unknown keys: brews",
+		},
+		{
+			name:    "entry without a name",
+			content: "[[formulae]]\n  version = \"1.7\"\n",
+			wantErr: "entry without a name",
+		},
+		{
+			name:    "formula listed twice",
+			content: "[[formulae]]\n  name = \"jq\"\n\n[[formulae]]\n  name = \"jq\"\n",
+			wantErr: "jq is listed more than once",
+		},
+		{
+			name:    "formula and cask with the same name",
+			content: "[[formulae]]\n  name = \"docker\"\n\n[[casks]]\n  name = \"docker\"\n",
+			wantErr: "docker is listed more than once",
+		},
+		{
+			name:    "not TOML",
+			content: "brew \"jq\"\n",
+			wantErr: "expected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	want := &Bundle{
+		Formulae: []Entry{{Name: "jq"}, {Name: "terraform", Version: "1.5.7"}},
+		Casks:    []Entry{{Name: "firefox", Pin: true}},
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := want.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load(Save(b)) = %+v, want %+v", got, want)
+	}
+}
+
+func TestEntryPinned(t *testing.T) {
+	tests := []struct {
+		entry Entry
+		want  bool
+	}{
+		{Entry{Name: "jq"}, false},
+		{Entry{Name: "jq", Pin: true}, true},
+		{Entry{Name: "jq", Version: "1.7"}, true},
+		{Entry{Name: "jq", SHA256: "abc"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.entry.Pinned(); got != tt.want {
+			t.Errorf("%+v.Pinned() = %v, want %v", tt.entry, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func newAutoremoveCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "autoremove",
+		Short: "Remove dependencies no installed package needs anymore",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, cfg, _, _, err := newManager()
+			if err != nil {
+				return err
+			}
+
+			orphans, err := mgr.Orphans()
+			if err != nil {
+				return err
+			}
+
+			if len(orphans) == 0 {
+				if jsonOutput {
+					return printJSON(newPackagesResult(nil))
+				}
+				fmt.Printf("%s No orphaned dependencies\n", dim("○"))
+				return nil
+			}
+
+			if dryRun {
+				if jsonOutput {
+					results := make([]packageResult, 0, len(orphans))
+					for _, pkg := range orphans {
+						results = append(results, newPackageResult(cfg, pkg, statusOrphaned))
+					}
+					return printJSON(newPackagesResult(results))
+				}
+				fmt.Println("Would remove:")
+				for _, pkg := range orphans {
+					fmt.Printf(" %s\n", bold(fmt.Sprintf("%s-%s", pkg.Name, pkg.FullVersion())))
+				}
+				return nil
+			}
+
+			if err := mgr.Begin(commandLine()); err != nil {
+				return err
+			}
+			defer mgr.Commit()
+
+			var failed int
+			var results []packageResult
+			for _, pkg := range orphans {
+				// An earlier removal may have already cascaded to this one
+				if installed, _, _ := mgr.IsInstalled(pkg.Name); installed {
+					if _, err := mgr.Remove(cmd.Context(), domain.Package{Name: pkg.Name}); err != nil {
+						results = append(results, failedResult(pkg.Name, err))
+						if !jsonOutput {
+							fmt.Printf("%s %s: %v\n", red("✗"), pkg.Name, err)
+						}
+						failed++
+						continue
+					}
+				}
+				results = append(results, newPackageResult(cfg, pkg, statusRemoved))
+				if !jsonOutput {
+					fmt.Printf("%s %s%s%s removed\n", green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()))
+				}
+			}
+
+			if err := mgr.Flush(); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+
+			if jsonOutput {
+				if err := printJSON(newPackagesResult(results)); err != nil {
+					return err
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("failed to remove %d package(s)", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List orphaned dependencies without removing them")
+	return cmd
+}
@@ -0,0 +1,604 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/bundle"
+	"github.com/teamcutter/chatr/internal/config"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+	"github.com/teamcutter/chatr/internal/registry"
+	"golang.org/x/sync/errgroup"
+)
+
+// Bundle entry states reported by bundle check
+const (
+	bundleOK       = "ok"
+	bundleMissing  = "missing"
+	bundleOutdated = "outdated"
+	bundleMismatch = "version_mismatch"
+	// bundleError is an installed entry the registry lookup failed for
+	bundleError = "error"
+	// bundleSkipped is a Brewfile package that chatr cannot install
+	bundleSkipped = "skipped"
+)
+
+type bundleStatus struct {
+	Name             string `json:"name"`
+	IsCask           bool   `json:"is_cask,omitempty"`
+	Status           string `json:"status"`
+	WantedVersion    string `json:"wanted_version,omitempty"`
+	InstalledVersion string `json:"installed_version,omitempty"`
+	LatestVersion    string `json:"latest_version,omitempty"`
+	Pinned           bool   `json:"pinned,omitempty"`
+	Error            string `json:"error,omitempty"`
+
+	entry bundle.Entry
+}
+
+func newBundleCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Install and check the packages listed in a Chatrfile",
+	}
+
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", bundle.FileName, "Path to the Chatrfile, or a Brewfile")
+	cmd.AddCommand(
+		newBundleInstallCmd(&file),
+		newBundleCheckCmd(&file),
+		newBundleDumpCmd(&file),
+		newBundleImportCmd(&file),
+	)
+	return cmd
+}
+
+func newBundleInstallCmd(file *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "install",
+		Short: "Install missing packages and fix versions listed in a Chatrfile",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("bundle install does not support --json, use bundle check --json")
+			}
+
+			ctx := cmd.Context()
+			cmd.SilenceUsage = true
+
+			b, skipped, err := loadBundle(ctx, *file)
+			if err != nil {
+				return err
+			}
+
+			return installBundle(ctx, b, len(skipped), *file, true)
+		},
+	}
+}
+
+// installBundle installs the missing entries of b and moves version
+// pinned ones to their version. With upgrade, outdated entries are
+// upgraded as well. skipped counts the packages of file, where b was read
+// from, that could not be imported and leave it unsatisfied.
+func installBundle(ctx context.Context, b *bundle.Bundle, skipped int, file string, upgrade bool) error {
+	statuses, err := checkBundle(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	var formulae, casks, upgrades []string
+	var restores, errored, held []bundleStatus
+	checksums := make(map[string]string)
+
+	for _, s := range statuses {
+		switch {
+		case s.Status == bundleOK:
+			if s.Pinned && s.LatestVersion != "" && s.LatestVersion != s.InstalledVersion {
+				held = append(held, s)
+			}
+		case s.Status == bundleError:
+			errored = append(errored, s)
+		case s.Status == bundleOutdated && !upgrade:
+		case s.WantedVersion != "" && s.WantedVersion != s.LatestVersion:
+			// The registry moved on, only the cache can still have it
+			restores = append(restores, s)
+		case s.Status == bundleMissing && s.IsCask:
+			casks = append(casks, s.Name)
+		case s.Status == bundleMissing:
+			formulae = append(formulae, s.Name)
+			if s.entry.SHA256 != "" {
+				checksums[s.Name] = s.entry.SHA256
+			}
+		default:
+			upgrades = append(upgrades, s.Name)
+		}
+	}
+
+	var failed int
+	for _, s := range errored {
+		fmt.Printf("%s %s: %s\n", red("✗"), s.Name, s.Error)
+		failed++
+	}
+	if len(formulae) > 0 {
+		if err := runInstall(ctx, formulae, installOptions{checksums: checksums}); err != nil {
+			failed++
+		}
+	}
+	if len(casks) > 0 {
+		if err := runInstall(ctx, casks, installOptions{cask: true}); err != nil {
+			failed++
+		}
+	}
+	if len(upgrades) > 0 {
+		if err := runUpgrade(ctx, upgrades, upgradeOptions{force: true}); err != nil {
+			failed++
+		}
+	}
+
+	mgr, _, _, _, err := newManager()
+	if err != nil {
+		return err
+	}
+
+	if len(restores) > 0 {
+		if err := restoreBundleVersions(ctx, mgr, restores); err != nil {
+			failed++
+		}
+	}
+
+	for _, s := range statuses {
+		if !s.entry.Pinned() {
+			continue
+		}
+		if installed, _, _ := mgr.IsInstalled(s.Name); installed {
+			mgr.SetPinned(s.Name, true)
+		}
+	}
+	if err := mgr.Flush(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	for _, s := range held {
+		fmt.Printf("%s %s is pinned at %s, %s is available, run chatr unpin %s to upgrade it\n",
+			dim("○"), s.Name, s.InstalledVersion, s.LatestVersion, s.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to install everything in %s", file)
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d package(s) in %s were skipped, chatr cannot install them", skipped, file)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s Everything in %s is installed\n", green("✓"), file)
+	return nil
+}
+
+// restoreBundleVersions installs pinned versions the registry no longer
+// offers from the cache. Their dependencies are not resolved, the
+// registry only knows those of the current version.
+func restoreBundleVersions(ctx context.Context, mgr *manager.Manager, restores []bundleStatus) error {
+	if err := mgr.Begin(commandLine()); err != nil {
+		return err
+	}
+	defer mgr.Commit()
+
+	fmt.Println()
+	var failed int
+	for _, s := range restores {
+		cached, err := mgr.CachedPackage(s.Name, s.WantedVersion)
+		var pkg *domain.InstalledPackage
+		if err == nil {
+			cached.IsCask = s.IsCask
+			pkg, err = mgr.Restore(ctx, cached)
+		}
+		if err != nil {
+			fmt.Printf("%s %s: %s wanted, registry has %s and %v\n",
+				red("✗"), s.Name, s.WantedVersion, s.LatestVersion, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s%s%s %s\n", green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(from cache)"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to restore %d package(s)", failed)
+	}
+	return nil
+}
+
+func newBundleCheckCmd(file *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Check that everything in a Chatrfile is installed and up to date",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			b, skipped, err := loadBundle(cmd.Context(), *file)
+			if err != nil {
+				return err
+			}
+
+			stop := withSpinner(cmd.Context(), "Checking...")
+			statuses, err := checkBundle(cmd.Context(), b)
+			stop()
+			if err != nil {
+				return err
+			}
+			statuses = append(statuses, skipped...)
+
+			var unsatisfied int
+			for _, s := range statuses {
+				if s.Status != bundleOK {
+					unsatisfied++
+				}
+			}
+
+			if jsonOutput {
+				if err := printJSON(statuses); err != nil {
+					return err
+				}
+			} else {
+				for _, s := range statuses {
+					fmt.Println(formatBundleStatus(s))
+				}
+			}
+
+			if unsatisfied > 0 {
+				return fmt.Errorf("%d package(s) in %s not satisfied, run chatr bundle install", unsatisfied, *file)
+			}
+			if !jsonOutput {
+				fmt.Printf("\n%s %s is satisfied\n", green("✓"), *file)
+			}
+			return nil
+		},
+	}
+}
+
+func newBundleDumpCmd(file *string) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Write the installed packages to a Chatrfile",
+		Long: `Write the packages installed on request to a Chatrfile. Dependencies
+are left out, pinned packages are written with their installed version.
+Use --file - to print it instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("bundle dump does not support --json")
+			}
+
+			mgr, _, _, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			installed, err := mgr.ListInstalled()
+			if err != nil {
+				return err
+			}
+
+			var b bundle.Bundle
+			for _, pkg := range installed {
+				if pkg.IsDep {
+					continue
+				}
+				e := bundle.Entry{Name: pkg.Name}
+				if pkg.Pinned {
+					e.Version = pkg.FullVersion()
+				}
+				if pkg.IsCask {
+					b.Casks = append(b.Casks, e)
+				} else {
+					b.Formulae = append(b.Formulae, e)
+				}
+			}
+			byName := func(a, b bundle.Entry) int { return strings.Compare(a.Name, b.Name) }
+			slices.SortFunc(b.Formulae, byName)
+			slices.SortFunc(b.Casks, byName)
+
+			return writeBundle(cmd, &b, *file, force)
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing Chatrfile")
+	return cmd
+}
+
+func newBundleImportCmd(file *string) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "import [Brewfile]",
+		Short: "Convert a Homebrew Brewfile into a Chatrfile",
+		Long: `Convert the brew, cask and tap lines of a Brewfile into a Chatrfile.
+Packages missing from the chatr indexes, third-party taps and lines such
+as mas, vscode or whalebrew are reported and left out.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("bundle import does not support --json")
+			}
+
+			src := "Brewfile"
+			if len(args) > 0 {
+				src = args[0]
+			}
+
+			b, _, err := importBrewfile(cmd.Context(), src)
+			if err != nil {
+				return err
+			}
+
+			return writeBundle(cmd, b, *file, force)
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing Chatrfile")
+	return cmd
+}
+
+// writeBundle saves b to path, or prints it when path is "-". An
+// existing file is only replaced with force.
+func writeBundle(cmd *cobra.Command, b *bundle.Bundle, path string, force bool) error {
+	if path == "-" {
+		return b.Write(os.Stdout)
+	}
+
+	if _, err := os.Stat(path); err == nil && !force {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err := b.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s Wrote %d formulae and %d casks to %s\n", green("✓"), len(b.Formulae), len(b.Casks), path)
+	return nil
+}
+
+// loadBundle reads a Chatrfile, or imports path if it is a Brewfile. The
+// packages of a Brewfile that could not be imported are returned as
+// skipped statuses, as nothing can satisfy them.
+func loadBundle(ctx context.Context, path string) (*bundle.Bundle, []bundleStatus, error) {
+	if !bundle.IsBrewfile(path) {
+		b, err := bundle.Load(path)
+		return b, nil, err
+	}
+
+	b, issues, err := importBrewfile(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var skipped []bundleStatus
+	for _, issue := range issues {
+		if issue.Package {
+			skipped = append(skipped, bundleStatus{Name: issue.Text, Status: bundleSkipped, Error: issue.Reason})
+		}
+	}
+	return b, skipped, nil
+}
+
+// importBrewfile parses the Brewfile at path and drops the packages the
+// formulae and cask indexes do not know. Every line that is not imported
+// as written is reported on stderr, so --json output stays clean, and
+// returned.
+func importBrewfile(ctx context.Context, path string) (*bundle.Bundle, []bundle.Issue, error) {
+	b, issues, err := bundle.ParseBrewfile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	formulae := registry.New(cfg.FormulaeDir)
+	casks := registry.NewCask(cfg.FormulaeDir)
+
+	stop := withSpinner(ctx, "Looking up packages...")
+	unknown := make([]bool, len(b.Formulae)+len(b.Casks))
+
+	// Only packages the index does not have are left out, any other
+	// lookup failure would silently drop packages that exist
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.MaxParallel)
+	for i := range unknown {
+		g.Go(func() error {
+			var err error
+			if i < len(b.Formulae) {
+				_, err = formulae.Get(gctx, b.Formulae[i].Name)
+			} else {
+				_, err = casks.Get(gctx, b.Casks[i-len(b.Formulae)].Name)
+			}
+			if errors.Is(err, registry.ErrNotFound) {
+				unknown[i] = true
+				return nil
+			}
+			return err
+		})
+	}
+	err = g.Wait()
+	stop()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up the packages of %s: %w", path, err)
+	}
+
+	var kept bundle.Bundle
+	for i, e := range b.Formulae {
+		if unknown[i] {
+			issues = append(issues, bundle.Issue{Text: `brew "` + e.Name + `"`, Reason: "not in the formulae index", Skipped: true, Package: true})
+			continue
+		}
+		kept.Formulae = append(kept.Formulae, e)
+	}
+	for i, e := range b.Casks {
+		if unknown[len(b.Formulae)+i] {
+			issues = append(issues, bundle.Issue{Text: `cask "` + e.Name + `"`, Reason: "not in the cask index", Skipped: true, Package: true})
+			continue
+		}
+		kept.Casks = append(kept.Casks, e)
+	}
+
+	for _, issue := range issues {
+		where := path
+		if issue.Line > 0 {
+			where = fmt.Sprintf("%s:%d", path, issue.Line)
+		}
+		symbol := yellow("!")
+		if issue.Skipped {
+			symbol = yellow("○")
+		}
+		fmt.Fprintf(os.Stderr, "%s %s %s: %s\n", symbol, dim(where), issue.Text, issue.Reason)
+	}
+
+	return &kept, issues, nil
+}
+
+// checkBundle compares every entry of b with the installed packages and
+// the registry, in the order of the file. Packages pinned with chatr pin
+// are never outdated.
+func checkBundle(ctx context.Context, b *bundle.Bundle) ([]bundleStatus, error) {
+	mgr, cfg, reg, _, err := newReadOnlyManager(false)
+	if err != nil {
+		return nil, err
+	}
+	caskReg := registry.NewCask(cfg.FormulaeDir)
+
+	installed, err := mgr.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []bundleStatus
+	for _, e := range b.Formulae {
+		statuses = append(statuses, bundleStatus{Name: e.Name, WantedVersion: e.Version, entry: e})
+	}
+	for _, e := range b.Casks {
+		statuses = append(statuses, bundleStatus{Name: e.Name, IsCask: true, WantedVersion: e.Version, entry: e})
+	}
+
+	mu := &sync.Mutex{}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.MaxParallel)
+
+	for i := range statuses {
+		g.Go(func() error {
+			s := &statuses[i]
+
+			r := domain.Registry(reg)
+			if s.IsCask {
+				r = caskReg
+			}
+			formula, err := r.Get(gctx, s.Name)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				s.Error = err.Error()
+			} else {
+				s.LatestVersion = formula.FullVersion()
+			}
+
+			pkg, ok := installed[s.Name]
+			if s.WantedVersion != "" {
+				var installedVersion string
+				if ok {
+					installedVersion = pkg.FullVersion()
+				}
+				s.WantedVersion = fullWanted(s.WantedVersion, installedVersion, s.LatestVersion)
+			}
+			switch {
+			case !ok:
+				s.Status = bundleMissing
+				return nil
+			case err != nil:
+				s.InstalledVersion = pkg.FullVersion()
+				s.Status = bundleError
+			case s.WantedVersion != "":
+				s.InstalledVersion = pkg.FullVersion()
+				s.Status = bundleOK
+				if s.InstalledVersion != s.WantedVersion {
+					s.Status = bundleMismatch
+				}
+			default:
+				s.InstalledVersion = pkg.FullVersion()
+				s.Pinned = pkg.Pinned
+				s.Status = bundleOK
+				// Upgrading would undo the pin, so pinned packages are
+				// only reported
+				if !s.entry.Pin && !pkg.Pinned && s.LatestVersion != "" && s.LatestVersion != s.InstalledVersion {
+					s.Status = bundleOutdated
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// fullWanted returns the first of fullVersions that wanted names, with or
+// without its revision, so that version = "1.7" in a Chatrfile matches
+// 1.7_1. wanted is returned as is when none of them match.
+func fullWanted(wanted string, fullVersions ...string) string {
+	for _, fv := range fullVersions {
+		if version, _ := domain.SplitVersion(fv); fv != "" && (wanted == fv || wanted == version) {
+			return fv
+		}
+	}
+	return wanted
+}
+
+func formatBundleStatus(s bundleStatus) string {
+	label := s.Name
+	if s.IsCask {
+		label += " " + dim("(cask)")
+	}
+
+	var line string
+	switch s.Status {
+	case bundleOK:
+		line = fmt.Sprintf("%s %s%s%s", green("✓"), bold(s.Name), bold("-"), bold(s.InstalledVersion))
+		if s.IsCask {
+			line += " " + dim("(cask)")
+		}
+		if s.Pinned && s.LatestVersion != "" && s.LatestVersion != s.InstalledVersion {
+			line += " " + dim("(pinned, "+s.LatestVersion+" available)")
+		}
+	case bundleMissing:
+		line = fmt.Sprintf("%s %s missing", red("✗"), label)
+		if s.WantedVersion != "" {
+			line += " " + dim("(wants "+s.WantedVersion+")")
+		}
+	case bundleOutdated:
+		line = fmt.Sprintf("%s %s %s → %s", yellow("↑"), label, s.InstalledVersion, s.LatestVersion)
+	case bundleMismatch:
+		line = fmt.Sprintf("%s %s %s installed, wants %s", yellow("!"), label, s.InstalledVersion, s.WantedVersion)
+	case bundleSkipped:
+		line = fmt.Sprintf("%s %s skipped", red("✗"), label)
+	case bundleError:
+		line = fmt.Sprintf("%s %s %s installed, cannot check", red("✗"), label, s.InstalledVersion)
+	}
+
+	if s.Error != "" && s.Status != bundleOK {
+		line += " " + dim("("+s.Error+")")
+	}
+	return line
+}
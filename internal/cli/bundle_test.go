@@ -0,0 +1,57 @@
+package cli
+
+import "testing"
+
+func TestFullWanted(t *testing.T) {
+	tests := []struct {
+		name         string
+		wanted       string
+		fullVersions []string
+		want         string
+	}{
+		{
+			name:         "full version",
+			wanted:       "1.7_1",
+			fullVersions: []string{"1.7_1", "1.7_2"},
+			want:         "1.7_1",
+		},
+		{
+			name:         "version without its revision",
+			wanted:       "1.7",
+			fullVersions: []string{"1.7_1"},
+			want:         "1.7_1",
+		},
+		{
+			name:         "installed version before the latest",
+			wanted:       "1.7",
+			fullVersions: []string{"1.7_1", "1.7_2"},
+			want:         "1.7_1",
+		},
+		{
+			name:         "not installed",
+			wanted:       "1.7",
+			fullVersions: []string{"", "1.7_2"},
+			want:         "1.7_2",
+		},
+		{
+			name:         "no match",
+			wanted:       "1.6",
+			fullVersions: []string{"1.7_1", ""},
+			want:         "1.6",
+		},
+		{
+			name:         "other revision",
+			wanted:       "1.7_1",
+			fullVersions: []string{"1.7_2"},
+			want:         "1.7_1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fullWanted(tt.wanted, tt.fullVersions...); got != tt.want {
+				t.Errorf("fullWanted(%q, %q) = %q, want %q", tt.wanted, tt.fullVersions, got, tt.want)
+			}
+		})
+	}
+}
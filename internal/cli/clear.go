@@ -24,6 +24,18 @@ func newClearCmd() *cobra.Command {
 			}
 
 			size, _ := c.Size()
+			if jsonOutput {
+				if size > 0 {
+					if err := c.Clear(); err != nil {
+						return fmt.Errorf("failed to clear cache: %w", err)
+					}
+				}
+				return printJSON(struct {
+					Path       string `json:"path"`
+					FreedBytes int64  `json:"freed_bytes"`
+				}{cfg.CacheDir, size})
+			}
+
 			if size == 0 {
 				fmt.Printf("%s Cache is empty\n", dim("○"))
 				return nil
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
+	"github.com/teamcutter/chatr/internal/registry"
+	"github.com/teamcutter/chatr/internal/state"
+)
+
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion <bash|zsh|fish>",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script. Package names are completed from
+the cached formulae index and the installed packages, without network access.
+
+  bash: source <(chatr completion bash)
+  zsh:  chatr completion zsh > "${fpath[1]}/_chatr"
+  fish: chatr completion fish > ~/.config/fish/completions/chatr.fish`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("completion does not support --json")
+			}
+
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			default:
+				return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", args[0])
+			}
+		},
+	}
+}
+
+// completeIndex completes formula names, or cask tokens when the command's
+// --cask flag is set, from the index cached in FormulaeDir.
+func completeIndex(multiple bool) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if !multiple && len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var names []string
+		if cask, _ := cmd.Flags().GetBool("cask"); cask {
+			names = registry.NewCask(cfg.FormulaeDir).CachedNames()
+		} else {
+			names = registry.New(cfg.FormulaeDir).CachedNames()
+		}
+
+		return filterCompletions(names, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeInstalled completes the names of installed packages.
+func completeInstalled(multiple bool) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if !multiple && len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		st, err := readState()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		defer st.Close()
+
+		installed, err := st.ListInstalled()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		names := make([]string, 0, len(installed))
+		for name := range installed {
+			names = append(names, name)
+		}
+
+		return filterCompletions(names, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// readState opens the state read-only. Completion runs on every TAB and
+// must not clean up installs still running in another chatr.
+func readState() (*state.SQLiteState, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return state.OpenReadOnly(cfg.StateDB)
+}
+
+// filterCompletions keeps names starting with toComplete that are not
+// already on the command line.
+func filterCompletions(names, args []string, toComplete string) []string {
+	var out []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) && !slices.Contains(args, name) {
+			out = append(out, name)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
+)
+
+// Sources of a setting, from lowest to highest precedence
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+)
+
+type configEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+type configPathResult struct {
+	Path string `json:"path"`
+}
+
+type configListResult struct {
+	Path    string        `json:"path"`
+	Entries []configEntry `json:"entries"`
+	Unknown []string      `json:"unknown_keys,omitempty"`
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show and change settings",
+	}
+
+	cmd.AddCommand(
+		newConfigListCmd(),
+		newConfigGetCmd(),
+		newConfigSetCmd(),
+		newConfigUnsetCmd(),
+		newConfigPathCmd(),
+		newConfigEditCmd(),
+	)
+	return cmd
+}
+
+func newConfigListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all settings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			fileKeys, err := config.FileKeys()
+			if err != nil {
+				return err
+			}
+
+			unknown, err := config.UnknownKeys()
+			if err != nil {
+				return err
+			}
+
+			res := configListResult{Path: config.Path(), Unknown: unknown}
+			for _, key := range config.Keys() {
+				value, _ := cfg.Get(key)
+
+				source := sourceDefault
+				if _, ok := os.LookupEnv(config.EnvName(key)); ok {
+					source = sourceEnv
+				} else if slices.Contains(fileKeys, key) {
+					source = sourceFile
+				}
+				res.Entries = append(res.Entries, configEntry{Key: key, Value: value, Source: source})
+			}
+
+			if jsonOutput {
+				return printJSON(res)
+			}
+
+			for _, e := range res.Entries {
+				line := fmt.Sprintf("%s = %s", cyan(e.Key), e.Value)
+				switch e.Source {
+				case sourceDefault:
+					line += " " + dim("(default)")
+				case sourceEnv:
+					line += " " + dim("(from "+config.EnvName(e.Key)+")")
+				}
+				fmt.Println(line)
+			}
+			printUnknownKeys(unknown)
+			return nil
+		},
+	}
+}
+
+func newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get <key>",
+		Short:             "Print the value of a setting",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			value, err := cfg.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(configEntry{Key: args[0], Value: value})
+			}
+			fmt.Println(value)
+			return nil
+		},
+	}
+}
+
+func newConfigSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "set <key> <value>",
+		Short:             "Change a setting",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadFile()
+			if err != nil {
+				return err
+			}
+
+			cmd.SilenceUsage = true
+			if err := cfg.Set(args[0], args[1]); err != nil {
+				return err
+			}
+			if err := saveConfig(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			value, _ := cfg.Get(args[0])
+			if jsonOutput {
+				return printJSON(configEntry{Key: args[0], Value: value, Source: sourceFile})
+			}
+			fmt.Printf("%s %s = %s\n", green("✓"), cyan(args[0]), value)
+			warnEnvOverride(args[0])
+			return nil
+		},
+	}
+}
+
+func newConfigUnsetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "unset <key>",
+		Short:             "Reset a setting to its default",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadFile()
+			if err != nil {
+				return err
+			}
+
+			if err := cfg.Unset(args[0]); err != nil {
+				return err
+			}
+			if err := saveConfig(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			value, _ := cfg.Get(args[0])
+			if jsonOutput {
+				return printJSON(configEntry{Key: args[0], Value: value, Source: sourceDefault})
+			}
+			fmt.Printf("%s %s = %s %s\n", green("✓"), cyan(args[0]), value, dim("(default)"))
+			warnEnvOverride(args[0])
+			return nil
+		},
+	}
+}
+
+func newConfigPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return printJSON(configPathResult{Path: config.Path()})
+			}
+			fmt.Println(config.Path())
+			return nil
+		},
+	}
+}
+
+func newConfigEditCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "Open the config file in $EDITOR",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("config edit does not support --json")
+			}
+
+			// Start from the defaults if there is no config file yet
+			if _, err := os.Stat(config.Path()); os.IsNotExist(err) {
+				if err := config.Save(config.DefaultConfig()); err != nil {
+					return fmt.Errorf("failed to create config: %w", err)
+				}
+			}
+
+			editor := os.Getenv("VISUAL")
+			if editor == "" {
+				editor = os.Getenv("EDITOR")
+			}
+			if editor == "" {
+				editor = "vi"
+			}
+
+			// Run through the shell so EDITOR may contain arguments
+			edit := exec.Command("sh", "-c", editor+` "$1"`, "sh", config.Path())
+			edit.Stdin = os.Stdin
+			edit.Stdout = os.Stdout
+			edit.Stderr = os.Stderr
+			if err := edit.Run(); err != nil {
+				return fmt.Errorf("editor failed: %w", err)
+			}
+
+			cmd.SilenceUsage = true
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("config file is invalid: %w", err)
+			}
+
+			unknown, err := config.UnknownKeys()
+			if err != nil {
+				return err
+			}
+			printUnknownKeys(unknown)
+
+			if err := cfg.Validate(); err != nil {
+				for _, e := range unwrapJoined(err) {
+					fmt.Printf("%s %v\n", red("✗"), e)
+				}
+				return fmt.Errorf("config has invalid values")
+			}
+			return nil
+		},
+	}
+}
+
+// saveConfig writes cfg back to the config file. Unknown keys cannot be
+// kept, so they are reported before they are dropped, on stderr so that
+// --json output stays clean.
+func saveConfig(cfg *config.Config) error {
+	unknown, err := config.UnknownKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range unknown {
+		fmt.Fprintf(os.Stderr, "%s removing unknown key %s\n", yellow("!"), bold(key))
+	}
+	return config.Save(cfg)
+}
+
+// warnEnvOverride reports when an environment variable hides the value
+// just written to the config file.
+func warnEnvOverride(key string) {
+	if value, ok := os.LookupEnv(config.EnvName(key)); ok {
+		fmt.Printf("%s %s=%s overrides this setting\n", yellow("!"), config.EnvName(key), value)
+	}
+}
+
+func printUnknownKeys(keys []string) {
+	for _, key := range keys {
+		fmt.Printf("%s unknown key %s in %s\n", yellow("!"), bold(key), config.Path())
+	}
+}
+
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCompletions(config.Keys(), args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type depsResult struct {
+	Name  string     `json:"name"`
+	Nodes []depsNode `json:"nodes"`
+}
+
+type depsNode struct {
+	Name         string   `json:"name"`
+	Dependencies []string `json:"dependencies"`
+	Installed    bool     `json:"installed"`
+}
+
+func newDepsCmd() *cobra.Command {
+	var format string
+	var installedOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "deps <name>",
+		Short: "Show the dependency graph of a package",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case "tree", "flat", "dot", "mermaid":
+			default:
+				return fmt.Errorf("unknown format %q (expected tree, flat, dot or mermaid)", format)
+			}
+
+			mgr, _, _, res, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			name := args[0]
+			graph := make(map[string][]string)
+			installed := make(map[string]bool)
+
+			if installedOnly {
+				pkgs, err := mgr.ListInstalled()
+				if err != nil {
+					return err
+				}
+				if _, ok := pkgs[name]; !ok {
+					return fmt.Errorf("package %s is not installed", name)
+				}
+				for n, pkg := range pkgs {
+					graph[n] = pkg.Dependencies
+					installed[n] = true
+				}
+			} else {
+				stop := withSpinner(cmd.Context(), fmt.Sprintf("Resolving %s...", name))
+				fetched, err := res.Graph(cmd.Context(), name)
+				stop()
+				if err != nil {
+					return err
+				}
+				for n, formula := range fetched {
+					graph[n] = formula.Dependencies
+					if ok, _, _ := mgr.IsInstalled(n); ok {
+						installed[n] = true
+					}
+				}
+			}
+
+			// --json replaces --format, nodes come in install order
+			if jsonOutput {
+				out := depsResult{Name: name, Nodes: []depsNode{}}
+				for _, n := range topoOrder(name, graph) {
+					deps := graph[n]
+					if deps == nil {
+						deps = []string{}
+					}
+					out.Nodes = append(out.Nodes, depsNode{Name: n, Dependencies: deps, Installed: installed[n]})
+				}
+				return printJSON(out)
+			}
+
+			switch format {
+			case "tree":
+				printDepsTree(name, graph, installed)
+			case "flat":
+				for _, n := range topoOrder(name, graph) {
+					if n == name {
+						continue
+					}
+					fmt.Println(depLabel(n, installed))
+				}
+			case "dot":
+				printDepsDot(name, graph, installed)
+			case "mermaid":
+				printDepsMermaid(name, graph, installed)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "tree", "Output format: tree, flat, dot or mermaid")
+	cmd.Flags().BoolVar(&installedOnly, "installed", false, "Show the graph recorded for installed packages")
+	return cmd
+}
+
+func depLabel(name string, installed map[string]bool) string {
+	if installed[name] {
+		return fmt.Sprintf("%s %s", name, green("✓"))
+	}
+	return name
+}
+
+func printDepsTree(root string, graph map[string][]string, installed map[string]bool) {
+	fmt.Println(bold(depLabel(root, installed)))
+
+	var walk func(name, prefix string, path map[string]bool)
+	walk = func(name, prefix string, path map[string]bool) {
+		deps := graph[name]
+		for i, dep := range deps {
+			branch, next := "├── ", "│   "
+			if i == len(deps)-1 {
+				branch, next = "└── ", "    "
+			}
+			if path[dep] {
+				fmt.Printf("%s%s%s %s\n", dim(prefix), dim(branch), dep, dim("(cycle)"))
+				continue
+			}
+			fmt.Printf("%s%s%s\n", dim(prefix), dim(branch), depLabel(dep, installed))
+			path[dep] = true
+			walk(dep, prefix+next, path)
+			delete(path, dep)
+		}
+	}
+	walk(root, "", map[string]bool{root: true})
+}
+
+// topoOrder returns every node reachable from root with dependencies
+// ordered before their dependents, mirroring the resolver install order.
+func topoOrder(root string, graph map[string][]string) []string {
+	var order []string
+	visited := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, dep := range graph[name] {
+			visit(dep)
+		}
+		order = append(order, name)
+	}
+	visit(root)
+
+	return order
+}
+
+func printDepsDot(root string, graph map[string][]string, installed map[string]bool) {
+	nodes := topoOrder(root, graph)
+
+	fmt.Println("digraph deps {")
+	fmt.Println("  rankdir=LR;")
+	for _, n := range nodes {
+		if installed[n] {
+			fmt.Printf("  %q [style=filled, fillcolor=palegreen];\n", n)
+		} else {
+			fmt.Printf("  %q;\n", n)
+		}
+	}
+	for _, n := range nodes {
+		for _, dep := range graph[n] {
+			fmt.Printf("  %q -> %q;\n", n, dep)
+		}
+	}
+	fmt.Println("}")
+}
+
+func printDepsMermaid(root string, graph map[string][]string, installed map[string]bool) {
+	nodes := topoOrder(root, graph)
+
+	ids := make(map[string]string, len(nodes))
+	for i, n := range nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+	}
+
+	fmt.Println("graph LR")
+	for _, n := range nodes {
+		fmt.Printf("  %s[\"%s\"]\n", ids[n], strings.ReplaceAll(n, `"`, "#quot;"))
+	}
+	for _, n := range nodes {
+		for _, dep := range graph[n] {
+			fmt.Printf("  %s --> %s\n", ids[n], ids[dep])
+		}
+	}
+
+	var marked []string
+	for _, n := range nodes {
+		if installed[n] {
+			marked = append(marked, ids[n])
+		}
+	}
+	if len(marked) > 0 {
+		fmt.Println("  classDef installed fill:#9f9")
+		fmt.Printf("  class %s installed\n", strings.Join(marked, ","))
+	}
+}
@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
+	"github.com/teamcutter/chatr/internal/registry"
+	"github.com/teamcutter/chatr/internal/state"
+)
+
+const staleIndexAge = 7 * 24 * time.Hour
+
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case checkWarn:
+		return "warn"
+	case checkFail:
+		return "fail"
+	default:
+		return "pass"
+	}
+}
+
+type checkResult struct {
+	status  checkStatus
+	message string
+	details []string
+}
+
+type doctorResult struct {
+	Checks   []doctorCheckResult `json:"checks"`
+	Failed   int                 `json:"failed"`
+	Warnings int                 `json:"warnings"`
+}
+
+type doctorCheckResult struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+type doctorCheck func(ctx context.Context, fix bool) checkResult
+
+func newDoctorCmd() *cobra.Command {
+	var fix bool
+
+	cmd := &cobra.Command{
+		Use:          "doctor",
+		Short:        "Check the chatr environment for problems",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Opening the state for writing cleans up interrupted installs,
+			// which is only done when asked to fix problems
+			var st *state.SQLiteState
+			if fix {
+				st, err = state.NewSQLite(cfg.StateDB, cfg.ManifestFile)
+			} else {
+				st, err = state.OpenReadOnly(cfg.StateDB)
+			}
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			// Stale package entries are fixed before dangling symlinks so
+			// that links left behind by removed entries are picked up too.
+			checks := []doctorCheck{
+				checkBinInPath(cfg),
+				checkPendingInstalls(st),
+				checkPackagePaths(cfg, st),
+				checkDanglingSymlinks(cfg.BinDir),
+				checkDanglingSymlinks(cfg.LibDir),
+				checkPatchelf(),
+				checkAppsDir(cfg),
+				checkIndex(cfg),
+			}
+
+			var failed, warned int
+			out := doctorResult{Checks: []doctorCheckResult{}}
+			for _, check := range checks {
+				res := check(cmd.Context(), fix)
+				if jsonOutput {
+					switch res.status {
+					case checkWarn:
+						warned++
+					case checkFail:
+						failed++
+					}
+					out.Checks = append(out.Checks, doctorCheckResult{Status: res.status.String(), Message: res.message, Details: res.details})
+					continue
+				}
+				switch res.status {
+				case checkPass:
+					fmt.Printf("%s %s\n", green("✓"), res.message)
+				case checkWarn:
+					warned++
+					fmt.Printf("%s %s\n", yellow("!"), res.message)
+				case checkFail:
+					failed++
+					fmt.Printf("%s %s\n", red("✗"), res.message)
+				}
+				for _, d := range res.details {
+					fmt.Printf("  %s %s\n", dim("↳"), d)
+				}
+			}
+
+			if fix {
+				if err := st.Flush(); err != nil {
+					return fmt.Errorf("failed to save state: %w", err)
+				}
+			}
+
+			if jsonOutput {
+				out.Failed, out.Warnings = failed, warned
+				if err := printJSON(out); err != nil {
+					return err
+				}
+				if failed > 0 {
+					cmd.SilenceErrors = true
+					return fmt.Errorf("%d check(s) failed, %d warning(s)", failed, warned)
+				}
+				return nil
+			}
+
+			fmt.Println()
+			if failed > 0 {
+				return fmt.Errorf("%d check(s) failed, %d warning(s)", failed, warned)
+			}
+			if warned > 0 {
+				fmt.Printf("%s %d warning(s)\n", yellow("!"), warned)
+				return nil
+			}
+			fmt.Printf("%s Everything looks good\n", green("✓"))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fix, "fix", false, "Repair problems that can be fixed safely")
+	return cmd
+}
+
+func checkBinInPath(cfg *config.Config) doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			if filepath.Clean(dir) == filepath.Clean(cfg.BinDir) {
+				return checkResult{status: checkPass, message: fmt.Sprintf("%s is in PATH", cfg.BinDir)}
+			}
+		}
+		return checkResult{
+			status:  checkFail,
+			message: fmt.Sprintf("%s is not in PATH", cfg.BinDir),
+			details: []string{fmt.Sprintf("add to your shell profile: export PATH=\"%s:$PATH\"", cfg.BinDir)},
+		}
+	}
+}
+
+func checkPendingInstalls(st *state.SQLiteState) doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		if recovered := st.Recovered(); len(recovered) > 0 {
+			return checkResult{status: checkPass, message: fmt.Sprintf("cleaned up %d interrupted install(s)", len(recovered)), details: recovered}
+		}
+		pending, err := st.Pending()
+		if err != nil {
+			return checkResult{status: checkFail, message: fmt.Sprintf("failed to read pending installs: %v", err)}
+		}
+		if len(pending) > 0 {
+			// Another chatr may still be installing them
+			return checkResult{
+				status:  checkFail,
+				message: fmt.Sprintf("%d pending install(s) in state, interrupted or still running (clean up with --fix)", len(pending)),
+				details: pending,
+			}
+		}
+		return checkResult{status: checkPass, message: "no interrupted installs"}
+	}
+}
+
+func checkPackagePaths(cfg *config.Config, st *state.SQLiteState) doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		installed, err := st.ListInstalled()
+		if err != nil {
+			return checkResult{status: checkFail, message: fmt.Sprintf("failed to read state: %v", err)}
+		}
+
+		var missing []string
+		for name, pkg := range installed {
+			paths := []string{pkg.Path}
+			if pkg.IsCask {
+				paths = paths[:0]
+				for _, app := range pkg.Apps {
+					paths = append(paths, filepath.Join(cfg.AppsDir, app))
+				}
+			}
+			for _, p := range paths {
+				if _, err := os.Stat(p); os.IsNotExist(err) {
+					missing = append(missing, name)
+					break
+				}
+			}
+		}
+		slices.Sort(missing)
+
+		if len(missing) == 0 {
+			return checkResult{status: checkPass, message: "all installed packages are present on disk"}
+		}
+
+		if fix {
+			var unfixed []string
+			for _, name := range missing {
+				if err := st.Remove(name); err != nil {
+					unfixed = append(unfixed, fmt.Sprintf("%s: %v", name, err))
+				}
+			}
+			if len(unfixed) > 0 {
+				return checkResult{status: checkFail, message: fmt.Sprintf("failed to remove %d of %d missing package(s) from state", len(unfixed), len(missing)), details: unfixed}
+			}
+			return checkResult{status: checkPass, message: fmt.Sprintf("removed %d missing package(s) from state", len(missing)), details: missing}
+		}
+
+		return checkResult{
+			status:  checkFail,
+			message: fmt.Sprintf("%d installed package(s) missing on disk", len(missing)),
+			details: missing,
+		}
+	}
+}
+
+func checkDanglingSymlinks(dir string) doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return checkResult{status: checkPass, message: fmt.Sprintf("no dangling symlinks in %s", dir)}
+		}
+		if err != nil {
+			return checkResult{status: checkFail, message: fmt.Sprintf("failed to read %s: %v", dir, err)}
+		}
+
+		var dangling []string
+		for _, e := range entries {
+			if e.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				dangling = append(dangling, e.Name())
+			}
+		}
+
+		if len(dangling) == 0 {
+			return checkResult{status: checkPass, message: fmt.Sprintf("no dangling symlinks in %s", dir)}
+		}
+
+		if fix {
+			var unfixed []string
+			for _, name := range dangling {
+				if err := os.Remove(filepath.Join(dir, name)); err != nil {
+					unfixed = append(unfixed, fmt.Sprintf("%s: %v", name, err))
+				}
+			}
+			if len(unfixed) > 0 {
+				return checkResult{status: checkWarn, message: fmt.Sprintf("failed to remove %d of %d dangling symlink(s) from %s", len(unfixed), len(dangling), dir), details: unfixed}
+			}
+			return checkResult{status: checkPass, message: fmt.Sprintf("removed %d dangling symlink(s) from %s", len(dangling), dir), details: dangling}
+		}
+
+		return checkResult{
+			status:  checkWarn,
+			message: fmt.Sprintf("%d dangling symlink(s) in %s", len(dangling), dir),
+			details: dangling,
+		}
+	}
+}
+
+func checkPatchelf() doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		if runtime.GOOS != "linux" {
+			return checkResult{status: checkPass, message: "patchelf not required on " + runtime.GOOS}
+		}
+		if _, err := exec.LookPath("patchelf"); err != nil {
+			return checkResult{
+				status:  checkWarn,
+				message: "patchelf not found, installed binaries may not run",
+				details: []string{"install with: apt install patchelf / dnf install patchelf"},
+			}
+		}
+		return checkResult{status: checkPass, message: "patchelf is available"}
+	}
+}
+
+func checkAppsDir(cfg *config.Config) doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		if runtime.GOOS != "darwin" {
+			return checkResult{status: checkPass, message: "casks not supported on " + runtime.GOOS}
+		}
+
+		if _, err := os.Stat(cfg.AppsDir); os.IsNotExist(err) {
+			if !fix {
+				return checkResult{status: checkFail, message: fmt.Sprintf("%s does not exist", cfg.AppsDir)}
+			}
+			if err := os.MkdirAll(cfg.AppsDir, 0755); err != nil {
+				return checkResult{status: checkFail, message: fmt.Sprintf("failed to create %s: %v", cfg.AppsDir, err)}
+			}
+		}
+
+		f, err := os.CreateTemp(cfg.AppsDir, ".chatr-doctor-*")
+		if err != nil {
+			return checkResult{status: checkFail, message: fmt.Sprintf("%s is not writable", cfg.AppsDir)}
+		}
+		f.Close()
+		os.Remove(f.Name())
+
+		return checkResult{status: checkPass, message: fmt.Sprintf("%s is writable", cfg.AppsDir)}
+	}
+}
+
+func checkIndex(cfg *config.Config) doctorCheck {
+	return func(ctx context.Context, fix bool) checkResult {
+		reg := registry.New(cfg.FormulaeDir)
+
+		updated, err := reg.IndexUpdatedAt()
+		age := time.Since(updated)
+		if err == nil && age <= staleIndexAge {
+			return checkResult{status: checkPass, message: fmt.Sprintf("formulae index updated %s ago", formatAge(age))}
+		}
+
+		if fix {
+			if err := reg.Refresh(ctx); err != nil {
+				return checkResult{status: checkFail, message: fmt.Sprintf("failed to refresh formulae index: %v", err)}
+			}
+			return checkResult{status: checkPass, message: "formulae index refreshed"}
+		}
+
+		if err != nil {
+			return checkResult{status: checkWarn, message: "formulae index has not been downloaded yet"}
+		}
+		return checkResult{
+			status:  checkWarn,
+			message: fmt.Sprintf("formulae index is stale (updated %s ago)", formatAge(age)),
+		}
+	}
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
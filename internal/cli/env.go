@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/project"
+)
+
+// projectBinEnv remembers which project bin directory env put on PATH,
+// so it can be taken off again when leaving the project.
+const projectBinEnv = "CHATR_PROJECT_BIN"
+
+type envResult struct {
+	Root   string `json:"root,omitempty"`
+	BinDir string `json:"bin_dir,omitempty"`
+}
+
+func newEnvCmd() *cobra.Command {
+	var shell string
+
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Print shell code that puts the project toolchain on PATH",
+		Long: `Print shell code that puts the bin directory of the project around the
+current directory first on PATH, and takes the one of a previous project
+off. Outside a project it only does the latter. Meant to be evaluated by
+the shell hook from chatr hook.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := project.Find(".")
+			if err != nil && !errors.Is(err, project.ErrNotFound) {
+				return err
+			}
+
+			if jsonOutput {
+				var res envResult
+				if p != nil {
+					res = envResult{Root: p.Root, BinDir: p.BinDir()}
+				}
+				return printJSON(res)
+			}
+
+			if shell == "" {
+				shell = filepath.Base(os.Getenv("SHELL"))
+			}
+
+			var binDir string
+			if p != nil {
+				binDir = p.BinDir()
+			}
+
+			switch shell {
+			case "bash", "zsh":
+				fmt.Print(posixEnv(binDir))
+			case "fish":
+				fmt.Print(fishEnv(binDir))
+			default:
+				return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", shell)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&shell, "shell", "", "Shell to print code for: bash, zsh or fish (default from $SHELL)")
+	return cmd
+}
+
+func newHookCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "hook <bash|zsh|fish>",
+		Short: "Print a shell hook that switches project toolchains on cd",
+		Long: `Print a shell hook that runs chatr env whenever the directory changes,
+so the tools declared in .chatr.toml come first on PATH inside a project.
+
+  bash: eval "$(chatr hook bash)"   in ~/.bashrc
+  zsh:  eval "$(chatr hook zsh)"    in ~/.zshrc
+  fish: chatr hook fish | source    in ~/.config/fish/config.fish`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("hook does not support --json")
+			}
+
+			switch args[0] {
+			case "bash":
+				fmt.Print(bashHook)
+			case "zsh":
+				fmt.Print(zshHook)
+			case "fish":
+				fmt.Print(fishHook)
+			default:
+				return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", args[0])
+			}
+			return nil
+		},
+	}
+}
+
+// Bash runs the hook before every prompt, which also catches pushd and
+// cd inside functions
+const bashHook = `_chatr_hook() {
+  local previous_exit_status=$?
+  eval "$(command chatr env --shell bash)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND:-};" != *";_chatr_hook;"* ]]; then
+  PROMPT_COMMAND="_chatr_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`
+
+const zshHook = `_chatr_hook() {
+  eval "$(command chatr env --shell zsh)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _chatr_hook
+_chatr_hook
+`
+
+const fishHook = `function _chatr_hook --on-variable PWD
+    command chatr env --shell fish | source
+end
+_chatr_hook
+`
+
+// posixEnv returns bash and zsh code that drops the previous project bin
+// directory from PATH and puts binDir first, if it is not empty.
+func posixEnv(binDir string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `if [ -n "${%[1]s:-}" ]; then PATH=":$PATH:"; PATH="${PATH//:$%[1]s:/:}"; PATH="${PATH#:}"; PATH="${PATH%%:}"; fi`+"\n", projectBinEnv)
+	if binDir == "" {
+		fmt.Fprintf(&b, "unset %s\n", projectBinEnv)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "export %s=%s\n", projectBinEnv, posixQuote(binDir))
+	fmt.Fprintf(&b, "export PATH=%s:\"$PATH\"\n", posixQuote(binDir))
+	return b.String()
+}
+
+func fishEnv(binDir string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "if set -q %[1]s; set -gx PATH (string match -v -- $%[1]s $PATH); end\n", projectBinEnv)
+	if binDir == "" {
+		fmt.Fprintf(&b, "set -e %s\n", projectBinEnv)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "set -gx %s %s\n", projectBinEnv, fishQuote(binDir))
+	fmt.Fprintf(&b, "set -gx PATH %s $PATH\n", fishQuote(binDir))
+	return b.String()
+}
+
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
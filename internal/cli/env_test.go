@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPosixEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		binDir string
+		// path is PATH before the code runs, previous the project bin
+		// directory it put on PATH before
+		path     string
+		previous string
+		want     string
+	}{
+		{
+			name:   "enter a project",
+			binDir: "/src/app/.chatr/bin",
+			path:   "/usr/bin:/bin",
+			want:   "/src/app/.chatr/bin:/usr/bin:/bin",
+		},
+		{
+			name:     "switch projects",
+			binDir:   "/src/lib/.chatr/bin",
+			path:     "/src/app/.chatr/bin:/usr/bin:/bin",
+			previous: "/src/app/.chatr/bin",
+			want:     "/src/lib/.chatr/bin:/usr/bin:/bin",
+		},
+		{
+			name:     "leave a project",
+			path:     "/usr/bin:/src/app/.chatr/bin:/bin",
+			previous: "/src/app/.chatr/bin",
+			want:     "/usr/bin:/bin",
+		},
+		{
+			name:     "previous directory last on PATH",
+			path:     "/usr/bin:/src/app/.chatr/bin",
+			previous: "/src/app/.chatr/bin",
+			want:     "/usr/bin",
+		},
+		{
+			name:   "quote in the path",
+			binDir: "/src/it's $HOME/.chatr/bin",
+			path:   "/usr/bin",
+			want:   "/src/it's $HOME/.chatr/bin:/usr/bin",
+		},
+		{
+			name:     "leave a project with a quote in the path",
+			path:     "/src/it's/.chatr/bin:/usr/bin",
+			previous: "/src/it's/.chatr/bin",
+			want:     "/usr/bin",
+		},
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := "PATH=" + posixQuote(tt.path) + "\n"
+			if tt.previous != "" {
+				script += projectBinEnv + "=" + posixQuote(tt.previous) + "\n"
+			}
+			script += posixEnv(tt.binDir)
+			script += `printf '%s\n%s' "$PATH" "${` + projectBinEnv + `-unset}"`
+
+			out, err := exec.Command(bash, "--norc", "--noprofile", "-c", script).Output()
+			if err != nil {
+				t.Fatalf("bash: %v\n%s", err, script)
+			}
+			gotPath, gotBin, _ := strings.Cut(string(out), "\n")
+			if gotPath != tt.want {
+				t.Errorf("PATH = %q, want %q", gotPath, tt.want)
+			}
+			wantBin := tt.binDir
+			if wantBin == "" {
+				wantBin = "unset"
+			}
+			if gotBin != wantBin {
+				t.Errorf("%s = %q, want %q", projectBinEnv, gotBin, wantBin)
+			}
+		})
+	}
+}
+
+func TestFishEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		binDir string
+		want   []string
+	}{
+		{
+			name: "outside a project",
+			want: []string{
+				"if set -q CHATR_PROJECT_BIN; set -gx PATH (string match -v -- $CHATR_PROJECT_BIN $PATH); end",
+				"set -e CHATR_PROJECT_BIN",
+			},
+		},
+		{
+			name:   "inside a project",
+			binDir: "/src/app/.chatr/bin",
+			want: []string{
+				"if set -q CHATR_PROJECT_BIN; set -gx PATH (string match -v -- $CHATR_PROJECT_BIN $PATH); end",
+				"set -gx CHATR_PROJECT_BIN '/src/app/.chatr/bin'",
+				"set -gx PATH '/src/app/.chatr/bin' $PATH",
+			},
+		},
+		{
+			name:   "quote and backslash in the path",
+			binDir: `/src/it's\app/.chatr/bin`,
+			want: []string{
+				"if set -q CHATR_PROJECT_BIN; set -gx PATH (string match -v -- $CHATR_PROJECT_BIN $PATH); end",
+				`set -gx CHATR_PROJECT_BIN '/src/it\'s\\app/.chatr/bin'`,
+				`set -gx PATH '/src/it\'s\\app/.chatr/bin' $PATH`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Split(strings.TrimSuffix(fishEnv(tt.binDir), "\n"), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("fishEnv(%q) =\n%s\nwant\n%s", tt.binDir, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/bundle"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+)
+
+func newExportCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the installed packages to a portable file",
+		Long: `Write the packages installed on request, with their full version,
+cask flag and pin, as JSON. Unlike installed.json it has no paths or
+dependency details, so chatr import can use it on another machine.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, _, _, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			export, err := mgr.Export()
+			if err != nil {
+				return err
+			}
+
+			if output == "" || output == "-" {
+				return printJSON(export)
+			}
+
+			data, err := json.MarshalIndent(export, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+				return err
+			}
+
+			fmt.Printf("%s Exported %d packages to %s\n", green("✓"), len(export.Packages), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write to a file instead of stdout")
+	return cmd
+}
+
+func newImportCmd() *cobra.Command {
+	var exact bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Install the packages listed by chatr export",
+		Long: `Install every package of an export that is missing. Pinned packages
+are installed at their exported version and pinned again, the others at
+the latest version. The export has no download URLs, so a pinned version
+the registry has moved on from can only come from the cache. Without it
+the latest version is installed and pinned instead, with a warning. Use -
+to read the export from stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("import does not support --json")
+			}
+
+			export, err := readExport(args[0])
+			if err != nil {
+				return err
+			}
+
+			var b bundle.Bundle
+			for _, p := range export.Packages {
+				e := bundle.Entry{Name: p.Name}
+				if p.Pinned {
+					e.Version = p.Version
+				}
+				if p.IsCask {
+					b.Casks = append(b.Casks, e)
+				} else {
+					b.Formulae = append(b.Formulae, e)
+				}
+			}
+
+			cmd.SilenceUsage = true
+			warnings, err := pinLatestIfUnavailable(cmd.Context(), &b)
+			if err != nil {
+				return err
+			}
+			for _, w := range warnings {
+				fmt.Printf("%s %s\n", yellow("!"), w)
+			}
+
+			if err := installBundle(cmd.Context(), &b, 0, args[0], false); err != nil {
+				return err
+			}
+
+			if exact {
+				return removeExtras(cmd, export)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&exact, "exact", false, "Also remove installed packages the export does not list")
+	return cmd
+}
+
+// pinLatestIfUnavailable moves the missing entries of b whose version
+// neither the registry nor the cache has to the latest version, pinned
+// once installed. It returns a warning for each of them.
+func pinLatestIfUnavailable(ctx context.Context, b *bundle.Bundle) ([]string, error) {
+	statuses, err := checkBundle(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr, _, _, _, err := newReadOnlyManager(false)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]string)
+	for _, s := range statuses {
+		if s.Status != bundleMissing || s.WantedVersion == "" || s.LatestVersion == "" ||
+			s.WantedVersion == s.LatestVersion || mgr.IsCached(s.Name, s.WantedVersion) {
+			continue
+		}
+		latest[s.Name] = s.LatestVersion
+	}
+
+	var warnings []string
+	for _, entries := range [][]bundle.Entry{b.Formulae, b.Casks} {
+		for i, e := range entries {
+			version, ok := latest[e.Name]
+			if !ok {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("%s %s is neither in the registry nor the cache, pinning %s instead",
+				e.Name, e.Version, version))
+			entries[i].Version = ""
+			entries[i].Pin = true
+		}
+	}
+	return warnings, nil
+}
+
+// dependents returns the installed packages other than name that depend
+// on it.
+func dependents(mgr *manager.Manager, name string) []string {
+	users, _ := mgr.Dependents(name)
+	return slices.DeleteFunc(users, func(user string) bool { return user == name })
+}
+
+func readExport(path string) (*manager.Export, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	export, err := manager.ReadExport(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return export, nil
+}
+
+// removeExtras removes the packages installed on request that export
+// does not list, along with the dependencies only they needed. Extras
+// that packages staying installed depend on are kept as dependencies.
+func removeExtras(cmd *cobra.Command, export *manager.Export) error {
+	mgr, _, _, _, err := newManager()
+	if err != nil {
+		return err
+	}
+
+	installed, err := mgr.ListInstalled()
+	if err != nil {
+		return err
+	}
+
+	remove, keep := planExtras(installed, export)
+	if len(remove) == 0 && len(keep) == 0 {
+		return nil
+	}
+
+	if err := mgr.Begin(commandLine()); err != nil {
+		return err
+	}
+	defer mgr.Commit()
+
+	fmt.Println()
+	var failed int
+	for _, name := range remove {
+		// An earlier removal may have already cascaded to this one
+		if installed, _, _ := mgr.IsInstalled(name); !installed {
+			continue
+		}
+		removed, err := mgr.Remove(cmd.Context(), domain.Package{Name: name})
+		if err != nil {
+			fmt.Printf("%s %s: %v\n", red("✗"), name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s%s%s removed %s\n", green("✓"), bold(removed.Name), bold("-"), bold(removed.FullVersion()), dim("(not in export)"))
+	}
+
+	for _, name := range keep {
+		if err := mgr.MarkDependency(name); err != nil {
+			fmt.Printf("%s %s: %v\n", red("✗"), name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s kept as a dependency of %s\n", dim("○"), bold(name), strings.Join(dependents(mgr, name), ", "))
+	}
+
+	if err := mgr.Flush(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to remove %d package(s)", failed)
+	}
+	return nil
+}
+
+// planExtras splits the packages installed on request that export does
+// not list into those to remove and those to keep, sorted by name. An
+// extra is kept when a package that stays installed needs it, directly
+// or through other dependencies.
+func planExtras(installed map[string]*domain.InstalledPackage, export *manager.Export) (remove, keep []string) {
+	listed := func(name string) bool {
+		return slices.ContainsFunc(export.Packages, func(p manager.ExportedPackage) bool { return p.Name == name })
+	}
+
+	needed := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		pkg, ok := installed[name]
+		if !ok || needed[name] {
+			return
+		}
+		needed[name] = true
+		for _, dep := range pkg.Dependencies {
+			visit(dep)
+		}
+	}
+	for name := range installed {
+		if listed(name) {
+			visit(name)
+		}
+	}
+
+	for name, pkg := range installed {
+		if pkg.IsDep || listed(name) {
+			continue
+		}
+		if needed[name] {
+			keep = append(keep, name)
+		} else {
+			remove = append(remove, name)
+		}
+	}
+	slices.Sort(remove)
+	slices.Sort(keep)
+	return remove, keep
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+)
+
+func TestPlanExtras(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []*domain.InstalledPackage
+		listed    []string
+		remove    []string
+		keep      []string
+	}{
+		{
+			name:      "everything listed",
+			installed: []*domain.InstalledPackage{{Name: "jq"}, {Name: "fd"}},
+			listed:    []string{"fd", "jq"},
+		},
+		{
+			name:      "extras are removed",
+			installed: []*domain.InstalledPackage{{Name: "jq"}, {Name: "fd"}, {Name: "rg"}},
+			listed:    []string{"jq"},
+			remove:    []string{"fd", "rg"},
+		},
+		{
+			name: "dependencies are never extras",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq", Dependencies: []string{"oniguruma"}},
+				{Name: "oniguruma", IsDep: true},
+			},
+			listed: []string{"jq"},
+		},
+		{
+			name: "extra needed by a listed package becomes a dependency",
+			installed: []*domain.InstalledPackage{
+				{Name: "git", Dependencies: []string{"pcre2"}},
+				{Name: "pcre2"},
+			},
+			listed: []string{"git"},
+			keep:   []string{"pcre2"},
+		},
+		{
+			name: "extra needed through a dependency becomes a dependency",
+			installed: []*domain.InstalledPackage{
+				{Name: "git", Dependencies: []string{"libgit2"}},
+				{Name: "libgit2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "openssl"},
+			},
+			listed: []string{"git"},
+			keep:   []string{"openssl"},
+		},
+		{
+			name: "extra needed only by other extras is removed",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq"},
+				{Name: "curl", Dependencies: []string{"libssh2"}},
+				{Name: "libssh2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "openssl"},
+			},
+			listed: []string{"jq"},
+			remove: []string{"curl", "openssl"},
+		},
+		{
+			name: "extras depending on each other are removed",
+			installed: []*domain.InstalledPackage{
+				{Name: "a", Dependencies: []string{"b"}},
+				{Name: "b", Dependencies: []string{"a"}},
+			},
+			remove: []string{"a", "b"},
+		},
+		{
+			name: "listed package installed as a dependency keeps its dependencies",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq", IsDep: true, Dependencies: []string{"oniguruma"}},
+				{Name: "oniguruma"},
+			},
+			listed: []string{"jq"},
+			keep:   []string{"oniguruma"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installed := make(map[string]*domain.InstalledPackage)
+			for _, pkg := range tt.installed {
+				installed[pkg.Name] = pkg
+			}
+			export := &manager.Export{}
+			for _, name := range tt.listed {
+				export.Packages = append(export.Packages, manager.ExportedPackage{Name: name, Version: "1.0"})
+			}
+
+			remove, keep := planExtras(installed, export)
+			if !slices.Equal(remove, tt.remove) {
+				t.Errorf("planExtras() remove = %q, want %q", remove, tt.remove)
+			}
+			if !slices.Equal(keep, tt.keep) {
+				t.Errorf("planExtras() keep = %q, want %q", keep, tt.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/resolver"
+	"golang.org/x/sync/errgroup"
+)
+
+func newFetchCmd() *cobra.Command {
+	var deps bool
+	var force bool
+	var cask bool
+
+	cmd := &cobra.Command{
+		Use:   "fetch <name>...",
+		Short: "Download packages into the cache without installing them",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Fetching installs nothing, the resolver only reads the state
+			mgr, cfg, _, res, err := newReadOnlyManager(cask)
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			ip := resolveInstallPlan(ctx, res, args, cfg.MaxParallel)
+
+			var plan []resolver.ResolvedPackage
+			for _, rp := range ip.packages {
+				if rp.IsDep && !deps {
+					continue
+				}
+				plan = append(plan, rp)
+			}
+
+			mu := &sync.Mutex{}
+			errs := ip.errs
+			fetched := make(map[string]packageResult)
+			output := make(map[string]string)
+
+			fg, fctx := errgroup.WithContext(ctx)
+			fg.SetLimit(cfg.MaxParallel)
+
+			for _, rp := range plan {
+				fg.Go(func() error {
+					formula := rp.Formula
+					path, cached, err := mgr.Fetch(fctx, toPackage(rp, ""), force)
+
+					mu.Lock()
+					defer mu.Unlock()
+
+					if err != nil {
+						errs = append(errs, fmt.Errorf("%s: %v", formula.Name, err))
+						fetched[formula.Name] = failedResult(formula.Name, err)
+						return nil
+					}
+
+					status := statusFetched
+					if cached {
+						status = statusAlreadyCached
+					}
+					fetched[formula.Name] = packageResult{
+						Name:    formula.Name,
+						Version: formula.FullVersion(),
+						Status:  status,
+						IsDep:   rp.IsDep,
+						IsCask:  formula.IsCask,
+						Cache:   path,
+					}
+
+					prefix := green("✓")
+					if rp.IsDep {
+						prefix = "  " + dim("↳")
+					}
+					line := fmt.Sprintf("%s %s%s%s", prefix, bold(formula.Name), bold("-"), bold(formula.FullVersion()))
+					if cached {
+						line += " " + dim("(already cached)")
+					}
+					output[formula.Name] = fmt.Sprintf("%s\n  %s %s", line, cyan("cache:"), path)
+					return nil
+				})
+			}
+			_ = fg.Wait()
+
+			if jsonOutput {
+				// Packages that failed to resolve are not in the plan
+				results := ip.failures
+				for _, rp := range plan {
+					results = append(results, fetched[rp.Formula.Name])
+				}
+				if err := printJSON(newPackagesResult(results)); err != nil {
+					return err
+				}
+				if len(errs) > 0 {
+					return fmt.Errorf("failed to fetch %d package(s)", len(errs))
+				}
+				return nil
+			}
+
+			fmt.Println()
+			for _, rp := range plan {
+				if msg, ok := output[rp.Formula.Name]; ok {
+					fmt.Println(msg)
+				}
+			}
+
+			if len(errs) > 0 {
+				for _, e := range errs {
+					fmt.Printf("%s %s\n", red("✗"), e)
+				}
+				return fmt.Errorf("failed to fetch %d package(s)", len(errs))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&deps, "deps", true, "Also fetch dependencies")
+	cmd.Flags().BoolVar(&force, "force", false, "Download again even if already cached")
+	cmd.Flags().BoolVar(&cask, "cask", false, "Fetch casks (macOS applications)")
+	return cmd
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func newHistoryCmd() *cobra.Command {
+	var since, until string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:               "history [name]",
+		Short:             "Show past installs, upgrades and removals",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeInstalled(false),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := parseTimeFlag(since, false)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			to, err := parseTimeFlag(until, true)
+			if err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+
+			mgr, _, _, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			transactions, err := mgr.Transactions()
+			if err != nil {
+				return err
+			}
+
+			var name string
+			if len(args) > 0 {
+				name = args[0]
+			}
+			shown := filterHistory(transactions, name, from, to, limit)
+
+			if jsonOutput {
+				if shown == nil {
+					shown = []*domain.Transaction{}
+				}
+				return printJSON(shown)
+			}
+
+			if len(shown) == 0 {
+				fmt.Printf("%s No history\n", dim("○"))
+				return nil
+			}
+
+			for i, t := range shown {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s %s %s %s\n",
+					outcomeSymbol(t.Outcome),
+					dim(fmt.Sprintf("#%d", t.ID)),
+					t.Time.Local().Format("2006-01-02 15:04"),
+					bold(t.Command))
+				if t.Outcome == domain.OutcomeRecovered {
+					fmt.Printf("  %s\n", yellow("interrupted, cleaned up on the next run"))
+				}
+				for _, c := range t.Changes {
+					fmt.Printf("  %s\n", formatChange(c))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only show transactions after a date (2006-01-02) or age (24h, 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "Only show transactions up to the end of a date (2006-01-02) or before an age (24h, 7d)")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "Show at most this many transactions")
+	return cmd
+}
+
+func outcomeSymbol(outcome string) string {
+	switch outcome {
+	case domain.OutcomeSuccess:
+		return green("✓")
+	case domain.OutcomeFailed:
+		return red("✗")
+	case domain.OutcomeRecovered:
+		return yellow("!")
+	default:
+		return dim("○")
+	}
+}
+
+func formatChange(c domain.PackageChange) string {
+	var line string
+	switch c.Action {
+	case domain.ActionInstall:
+		line = fmt.Sprintf("%s %s %s", green("+"), c.Name, c.NewVersion)
+	case domain.ActionUpgrade:
+		line = fmt.Sprintf("%s %s %s → %s", cyan("↑"), c.Name, c.OldVersion, c.NewVersion)
+	case domain.ActionReinstall:
+		line = fmt.Sprintf("%s %s %s", cyan("↻"), c.Name, c.NewVersion)
+	case domain.ActionRemove:
+		line = fmt.Sprintf("%s %s %s", red("-"), c.Name, c.OldVersion)
+	case domain.ActionSwitch:
+		line = fmt.Sprintf("%s %s %s → %s", cyan("⇄"), c.Name, c.OldVersion, c.NewVersion)
+	default:
+		line = fmt.Sprintf("%s %s", dim("?"), c.Name)
+	}
+	line = strings.TrimSpace(line)
+
+	if c.IsCask {
+		line += " " + dim("(cask)")
+	}
+	if c.IsDep {
+		line += " " + dim("(dependency)")
+	}
+	if c.Inactive {
+		line += " " + dim("(inactive)")
+	}
+
+	switch c.Outcome {
+	case domain.OutcomeFailed:
+		line += " " + red("failed: "+c.Error)
+	case domain.OutcomeRecovered:
+		line += " " + yellow("(interrupted)")
+	}
+	return line
+}
+
+// filterHistory keeps the transactions touching name, or all of them
+// when name is empty, from from up to but excluding to. Zero times do
+// not bound the range and a limit of 0 keeps every match.
+func filterHistory(transactions []*domain.Transaction, name string, from, to time.Time, limit int) []*domain.Transaction {
+	var shown []*domain.Transaction
+	for _, t := range transactions {
+		if name != "" && !t.Touches(name) {
+			continue
+		}
+		if !from.IsZero() && t.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !t.Time.Before(to) {
+			continue
+		}
+		shown = append(shown, t)
+		if limit > 0 && len(shown) == limit {
+			break
+		}
+	}
+	return shown
+}
+
+// parseTimeFlag accepts a date, a date and time, or an age such as 24h
+// or 7d counted back from now. An empty value returns the zero time.
+// With end set the result is an exclusive upper bound, so a date or a
+// date and time includes the whole day or minute it names.
+func parseTimeFlag(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		if end {
+			return t.AddDate(0, 0, 1), nil
+		}
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		if end {
+			return t.Add(time.Minute), nil
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02) or age (24h, 7d)", value)
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func TestParseTimeFlag(t *testing.T) {
+	tests := []struct {
+		value string
+		// end parses value as an exclusive --until bound
+		end bool
+		// want is compared exactly, age against now with some slack
+		want    time.Time
+		age     time.Duration
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "2024-03-01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2024-03-01 14:30", want: time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local)},
+		{value: "2024-03-01T14:30:00Z", want: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{value: "2024-03-01", end: true, want: time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local)},
+		{value: "2024-12-31", end: true, want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2024-03-01 14:30", end: true, want: time.Date(2024, 3, 1, 14, 31, 0, 0, time.Local)},
+		{value: "2024-03-01T14:30:00Z", end: true, want: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{value: "24h", end: true, age: 24 * time.Hour},
+		{value: "24h", age: 24 * time.Hour},
+		{value: "90m", age: 90 * time.Minute},
+		{value: "7d", age: 7 * 24 * time.Hour},
+		{value: "0d", age: 0},
+		{value: "d", wantErr: true},
+		{value: "7w", wantErr: true},
+		{value: "yesterday", wantErr: true},
+		{value: "2024-13-01", wantErr: true},
+		{value: "01/03/2024", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimeFlag(tt.value, tt.end)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTimeFlag(%q, %v) = %v, want an error", tt.value, tt.end, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTimeFlag(%q, %v): %v", tt.value, tt.end, err)
+			}
+
+			if !tt.want.IsZero() || tt.value == "" {
+				if !got.Equal(tt.want) {
+					t.Errorf("parseTimeFlag(%q, %v) = %v, want %v", tt.value, tt.end, got, tt.want)
+				}
+				return
+			}
+
+			slack := time.Minute
+			if strings.HasSuffix(tt.value, "d") {
+				// Days are calendar days, which DST can make an hour off
+				slack += time.Hour
+			}
+			age := time.Since(got)
+			if diff := (age - tt.age).Abs(); diff > slack {
+				t.Errorf("parseTimeFlag(%q, %v) is %v ago, want %v", tt.value, tt.end, age, tt.age)
+			}
+		})
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	at := func(id int64, day, hour int, names ...string) *domain.Transaction {
+		tx := &domain.Transaction{ID: id, Time: time.Date(2026, 10, day, hour, 0, 0, 0, time.Local)}
+		for _, name := range names {
+			tx.Changes = append(tx.Changes, domain.PackageChange{Name: name})
+		}
+		return tx
+	}
+	// Newest first, as Transactions returns them
+	transactions := []*domain.Transaction{
+		at(4, 16, 9, "jq"),
+		at(3, 15, 23, "fd"),
+		at(2, 15, 8, "jq", "oniguruma"),
+		at(1, 14, 12, "jq"),
+	}
+
+	tests := []struct {
+		name         string
+		pkg          string
+		since, until string
+		limit        int
+		want         []int64
+	}{
+		{name: "everything", want: []int64{4, 3, 2, 1}},
+		{name: "by package", pkg: "jq", want: []int64{4, 2, 1}},
+		{name: "one day", since: "2026-10-15", until: "2026-10-15", want: []int64{3, 2}},
+		{name: "until a date includes it", until: "2026-10-15", want: []int64{3, 2, 1}},
+		{name: "until a minute includes it", until: "2026-10-15 08:00", want: []int64{2, 1}},
+		{name: "since a date", since: "2026-10-15", pkg: "jq", want: []int64{4, 2}},
+		{name: "limit", limit: 2, want: []int64{4, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, err := parseTimeFlag(tt.since, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := parseTimeFlag(tt.until, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int64
+			for _, tx := range filterHistory(transactions, tt.pkg, from, to, tt.limit) {
+				got = append(got, tx.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/registry"
+)
+
+type infoResult struct {
+	Name         string         `json:"name"`
+	Version      string         `json:"version,omitempty"`
+	Description  string         `json:"description,omitempty"`
+	Homepage     string         `json:"homepage,omitempty"`
+	URL          string         `json:"url,omitempty"`
+	SHA256       string         `json:"sha256,omitempty"`
+	Dependencies []string       `json:"dependencies,omitempty"`
+	IsCask       bool           `json:"is_cask,omitempty"`
+	Apps         []string       `json:"apps,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	Installed    *packageResult `json:"installed,omitempty"`
+	InstalledAt  *time.Time     `json:"installed_at,omitempty"`
+}
+
+func newInfoCmd() *cobra.Command {
+	var cask bool
+
+	cmd := &cobra.Command{
+		Use:               "info <name>",
+		Short:             "Show detailed information about a package",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeIndex(false),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, cfg, reg, _, err := newReadOnlyManager(cask)
+			if err != nil {
+				return err
+			}
+
+			name := args[0]
+
+			_, installedPkg, err := mgr.IsInstalled(name)
+			if err != nil {
+				return err
+			}
+
+			// Installed casks are detected from state, same as upgrade
+			if installedPkg != nil && installedPkg.IsCask && !cask {
+				reg = registry.NewCask(cfg.FormulaeDir)
+			}
+
+			stop := withSpinner(cmd.Context(), fmt.Sprintf("Fetching %s...", name))
+			formula, regErr := reg.Get(cmd.Context(), name)
+			stop()
+
+			if regErr != nil && installedPkg == nil {
+				return regErr
+			}
+
+			if jsonOutput {
+				out := infoResult{Name: name}
+				if formula != nil {
+					out.Version = formula.FullVersion()
+					out.Description = formula.Description
+					out.Homepage = formula.Homepage
+					out.URL = formula.URL
+					out.SHA256 = formula.SHA256
+					out.Dependencies = formula.Dependencies
+					out.IsCask = formula.IsCask
+					out.Apps = formula.Apps
+				} else {
+					out.Error = regErr.Error()
+				}
+				if installedPkg != nil {
+					res := newPackageResult(cfg, installedPkg, statusInstalled)
+					out.Installed = &res
+					out.InstalledAt = &installedPkg.InstalledAt
+				}
+				return printJSON(out)
+			}
+
+			fmt.Printf("%s %s\n", green("●"), bold(name))
+
+			if formula != nil {
+				fmt.Printf("  %s %s\n", cyan("version:"), formula.FullVersion())
+				if formula.Description != "" {
+					fmt.Printf("  %s %s\n", cyan("desc:"), formula.Description)
+				}
+				if formula.Homepage != "" {
+					fmt.Printf("  %s %s\n", cyan("homepage:"), dim(formula.Homepage))
+				}
+				if formula.URL != "" {
+					fmt.Printf("  %s %s\n", cyan("url:"), dim(formula.URL))
+				}
+				if formula.SHA256 != "" {
+					fmt.Printf("  %s %s\n", cyan("sha256:"), dim(formula.SHA256))
+				}
+				if len(formula.Dependencies) > 0 {
+					fmt.Printf("  %s %s\n", cyan("deps:"), strings.Join(formula.Dependencies, ", "))
+				}
+				if formula.IsCask && len(formula.Apps) > 0 {
+					fmt.Printf("  %s %s\n", cyan("apps:"), strings.Join(formula.Apps, ", "))
+				}
+			} else {
+				fmt.Printf("  %s %s\n", cyan("version:"), dim(fmt.Sprintf("unknown (%v)", regErr)))
+			}
+
+			fmt.Println()
+
+			if installedPkg == nil {
+				fmt.Printf("%s Not installed\n", dim("○"))
+				return nil
+			}
+
+			status := installedPkg.FullVersion()
+			if formula != nil && formula.FullVersion() != installedPkg.FullVersion() {
+				status += "  " + yellow(fmt.Sprintf("↑ %s", formula.FullVersion()))
+			}
+			if installedPkg.IsDep {
+				status += " " + dim("(dependency)")
+			}
+			fmt.Printf("%s Installed %s\n", green("✓"), bold(status))
+
+			if installedPkg.IsCask {
+				for _, app := range installedPkg.Apps {
+					fmt.Printf("  %s %s\n", cyan("app:"), filepath.Join(cfg.AppsDir, app))
+				}
+			} else {
+				fmt.Printf("  %s %s\n", cyan("path:"), installedPkg.Path)
+				for _, bin := range installedPkg.Binaries {
+					fmt.Printf("  %s %s\n", cyan("bin:"), filepath.Join(cfg.BinDir, bin))
+				}
+				for _, lib := range installedPkg.Libs {
+					fmt.Printf("  %s %s\n", cyan("lib:"), filepath.Join(cfg.LibDir, lib))
+				}
+			}
+			if len(installedPkg.Dependencies) > 0 {
+				fmt.Printf("  %s %s\n", cyan("deps:"), strings.Join(installedPkg.Dependencies, ", "))
+			}
+			fmt.Printf("  %s %s\n", cyan("installed:"), installedPkg.InstalledAt.Local().Format(time.DateTime))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&cask, "cask", false, "Show a cask (macOS application)")
+	return cmd
+}
@@ -1,13 +1,18 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
 	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/lock"
 	"github.com/teamcutter/chatr/internal/resolver"
 	"golang.org/x/sync/errgroup"
 )
@@ -15,167 +20,317 @@ import (
 func newInstallCmd() *cobra.Command {
 	var sha256 string
 	var cask bool
+	var dryRun bool
+	var locked bool
+	var writeLock bool
+	var lockFile string
 
 	cmd := &cobra.Command{
 		Use:   "install <name>...",
 		Short: "Install packages",
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Install packages and their dependencies.
+
+With --lock the installed packages are written to the lockfile. With
+--locked they are installed exactly as locked, whatever the index says
+now; without names every package in the lockfile is installed.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if locked {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: completeIndex(true),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			mgr, cfg, _, res, err := newManagerWithOptions(cask)
-			if err != nil {
-				return err
+			opts := installOptions{cask: cask, dryRun: dryRun}
+			if sha256 != "" {
+				opts.checksums = make(map[string]string)
+				for _, name := range args {
+					opts.checksums[name] = sha256
+				}
 			}
-
-			ctx := cmd.Context()
-			mu := &sync.Mutex{}
-			var errs []error
-
-			resolved := make([][]resolver.ResolvedPackage, len(args))
-
-			rg, rctx := errgroup.WithContext(ctx)
-			rg.SetLimit(min(len(args), cfg.MaxParallel))
-
-			for i, name := range args {
-				rg.Go(func() error {
-					stop := withSpinner(rctx, fmt.Sprintf("Resolving %s...", name))
-					pkgs, err := res.Resolve(rctx, name)
-					stop()
-					if err != nil {
-						mu.Lock()
-						errs = append(errs, fmt.Errorf("%s: %v", name, err))
-						mu.Unlock()
-						return nil
-					}
-					resolved[i] = pkgs
-					return nil
-				})
+			if locked {
+				l, err := lock.Load(lockFile)
+				if err != nil {
+					return err
+				}
+				opts.lock = l
+				if len(args) == 0 {
+					args = l.Roots()
+				}
+			}
+			if writeLock {
+				opts.lockFile = lockFile
 			}
-			_ = rg.Wait()
+			return runInstall(cmd.Context(), args, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&sha256, "sha256", "", "Expected SHA256 checksum")
+	cmd.Flags().BoolVar(&cask, "cask", false, "Install a cask (macOS application)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be installed without changing anything")
+	cmd.Flags().BoolVar(&locked, "locked", false, "Install the exact versions from the lockfile")
+	cmd.Flags().BoolVar(&writeLock, "lock", false, "Write the installed packages to the lockfile")
+	cmd.Flags().StringVar(&lockFile, "lock-file", lock.FileName, "Path to the lockfile")
+	cmd.MarkFlagsMutuallyExclusive("locked", "lock")
+	cmd.MarkFlagsMutuallyExclusive("locked", "sha256")
+	cmd.MarkFlagsMutuallyExclusive("locked", "cask")
+	return cmd
+}
 
-			seen := make(map[string]bool)
-			var plan []resolver.ResolvedPackage
-			rootDeps := make(map[string][]string)
+type installOptions struct {
+	cask   bool
+	dryRun bool
+	// checksums overrides the registry SHA256 of root packages by name
+	checksums map[string]string
+	// lock, if set, is resolved against instead of the index
+	lock *lock.Lock
+	// lockFile, if set, is updated with the installed packages
+	lockFile string
+}
 
-			for _, pkgs := range resolved {
-				if len(pkgs) == 0 {
-					continue
-				}
+// runInstall installs names and their dependencies and prints the result.
+func runInstall(ctx context.Context, names []string, opts installOptions) error {
+	newRegistry := registryFor(opts.cask)
+	if opts.lock != nil {
+		newRegistry = func(*config.Config) domain.Registry {
+			return opts.lock.Registry()
+		}
+	}
+	mgr, cfg, _, res, err := openManager(newRegistry, stateFor(opts.dryRun))
+	if err != nil {
+		return err
+	}
 
-				rootName := pkgs[len(pkgs)-1].Formula.Name
+	ip := resolveInstallPlan(ctx, res, names, cfg.MaxParallel)
+	plan, rootDeps := ip.packages, ip.rootDeps
 
-				for _, rp := range pkgs {
-					name := rp.Formula.Name
-					if rp.IsDep || rp.AlreadyInstalled {
-						rootDeps[rootName] = append(rootDeps[rootName], name)
-					}
-					if !seen[name] {
-						seen[name] = true
-						plan = append(plan, rp)
-					}
-				}
-			}
+	mu := &sync.Mutex{}
+	errs := ip.errs
 
-			output := make(map[string]string)
-			outMu := &sync.Mutex{}
+	if opts.dryRun {
+		return printInstallPlan(ctx, mgr, plan, opts.checksums, ip.failures)
+	}
 
-			ig, ictx := errgroup.WithContext(ctx)
-			ig.SetLimit(cfg.MaxParallel)
+	if err := mgr.Begin(commandLine()); err != nil {
+		return err
+	}
+	defer mgr.Commit()
 
-			for _, rp := range plan {
-				ig.Go(func() error {
-					formula := rp.Formula
+	// Locked packages installed at another version are moved to the
+	// locked one instead of being skipped
+	relock := make(map[string]*domain.InstalledPackage)
+	if opts.lock != nil {
+		installed, err := mgr.ListInstalled()
+		if err != nil {
+			return err
+		}
+		for _, rp := range plan {
+			if current, ok := installed[rp.Formula.Name]; ok && current.FullVersion() != rp.Formula.FullVersion() {
+				relock[rp.Formula.Name] = current
+			}
+		}
+	}
 
-					if rp.AlreadyInstalled {
-						outMu.Lock()
-						output[formula.Name] = fmt.Sprintf("  %s %s %s",
-							dim("↳"), formula.Name, dim("(already installed)"))
-						outMu.Unlock()
-						return nil
-					}
+	output := make(map[string]string)
+	results := make(map[string]packageResult)
+	outMu := &sync.Mutex{}
 
-					checksum := formula.SHA256
-					if !rp.IsDep && sha256 != "" {
-						checksum = sha256
-					}
+	ig, ictx := errgroup.WithContext(ctx)
+	ig.SetLimit(cfg.MaxParallel)
 
-					pkg, err := mgr.Install(ictx, domain.Package{
-						Name:        formula.Name,
-						Version:     formula.Version,
-						Revision:    formula.Revision,
-						FullVersion: formula.FullVersion(),
-						DownloadURL: formula.URL,
-						SHA256:      checksum,
-						IsDep:       rp.IsDep,
-						IsCask:      formula.IsCask,
-					})
-					if err != nil {
-						outMu.Lock()
-						if strings.Contains(err.Error(), "already installed") {
-							output[formula.Name] = fmt.Sprintf("%s %s already installed", yellow("!"), bold(formula.Name))
-						} else if rp.IsDep {
-							output[formula.Name] = fmt.Sprintf("  %s %s: %v %s",
-								dim("↳"), formula.Name, err, dim("(skipped)"))
-						} else {
-							mu.Lock()
-							errs = append(errs, fmt.Errorf("%s: %v", formula.Name, err))
-							mu.Unlock()
-						}
-						outMu.Unlock()
-						return nil
-					}
+	for _, rp := range plan {
+		ig.Go(func() error {
+			formula := rp.Formula
 
-					outMu.Lock()
-					if rp.IsDep {
-						output[formula.Name] = fmt.Sprintf("  %s %s%s%s %s",
-							dim("↳"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(dependency)"))
-					} else if pkg.IsCask {
-						lines := fmt.Sprintf("%s %s%s%s %s",
-							green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(cask)"))
-						for _, app := range pkg.Apps {
-							lines += fmt.Sprintf("\n  %s %s", cyan("app:"), filepath.Join(cfg.AppsDir, app))
-						}
-						output[formula.Name] = lines
-					} else {
-						output[formula.Name] = fmt.Sprintf("%s %s%s%s\n  %s %s\n  %s %s",
-							green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()),
-							cyan("cache:"), filepath.Join(cfg.CacheDir, pkg.Name, pkg.FullVersion()),
-							cyan("path:"), filepath.Join(cfg.PackagesDir, pkg.Name, pkg.FullVersion()))
-					}
-					outMu.Unlock()
+			if current, ok := relock[formula.Name]; ok {
+				pkg := toPackage(rp, "")
+				pkg.IsDep = current.IsDep
+				upgraded, err := mgr.Upgrade(ictx, domain.Package{Name: current.Name, FullVersion: current.FullVersion()}, pkg)
+
+				outMu.Lock()
+				defer outMu.Unlock()
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %v", formula.Name, err))
+					mu.Unlock()
+					results[formula.Name] = failedResult(formula.Name, err)
 					return nil
-				})
+				}
+				result := newPackageResult(cfg, upgraded, statusUpgraded)
+				result.OldVersion = current.FullVersion()
+				results[formula.Name] = result
+				output[formula.Name] = fmt.Sprintf("%s %s%s%s → %s %s",
+					green("✓"), bold(upgraded.Name), bold("-"), bold(current.FullVersion()), bold(upgraded.FullVersion()), dim("(locked)"))
+				return nil
 			}
-			_ = ig.Wait()
 
-			for root, deps := range rootDeps {
-				if len(deps) > 0 {
-					mgr.SetDependencies(root, deps)
+			if rp.AlreadyInstalled {
+				outMu.Lock()
+				output[formula.Name] = fmt.Sprintf("  %s %s %s",
+					dim("↳"), formula.Name, dim("(already installed)"))
+				results[formula.Name] = packageResult{
+					Name:   formula.Name,
+					Status: statusAlreadyInstalled,
+					IsDep:  true,
 				}
+				outMu.Unlock()
+				return nil
 			}
 
-			if err := mgr.Flush(); err != nil {
-				return fmt.Errorf("failed to save state: %w", err)
+			pkg, err := mgr.Install(ictx, toPackage(rp, opts.checksums[formula.Name]))
+			if err != nil {
+				outMu.Lock()
+				if strings.Contains(err.Error(), "already installed") {
+					output[formula.Name] = fmt.Sprintf("%s %s already installed", yellow("!"), bold(formula.Name))
+					results[formula.Name] = packageResult{
+						Name:   formula.Name,
+						Status: statusAlreadyInstalled,
+						IsDep:  rp.IsDep,
+					}
+				} else if rp.IsDep {
+					output[formula.Name] = fmt.Sprintf("  %s %s: %v %s",
+						dim("↳"), formula.Name, err, dim("(skipped)"))
+					results[formula.Name] = packageResult{
+						Name:   formula.Name,
+						Status: statusSkipped,
+						IsDep:  true,
+						Error:  err.Error(),
+					}
+				} else {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %v", formula.Name, err))
+					mu.Unlock()
+					results[formula.Name] = failedResult(formula.Name, err)
+				}
+				outMu.Unlock()
+				return nil
 			}
 
-			fmt.Println()
-			for _, rp := range plan {
-				if msg, ok := output[rp.Formula.Name]; ok {
-					fmt.Println(msg)
+			outMu.Lock()
+			results[formula.Name] = newPackageResult(cfg, pkg, statusInstalled)
+			if rp.IsDep {
+				output[formula.Name] = fmt.Sprintf("  %s %s%s%s %s",
+					dim("↳"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(dependency)"))
+			} else if pkg.IsCask {
+				lines := fmt.Sprintf("%s %s%s%s %s",
+					green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(cask)"))
+				for _, app := range pkg.Apps {
+					lines += fmt.Sprintf("\n  %s %s", cyan("app:"), filepath.Join(cfg.AppsDir, app))
 				}
+				output[formula.Name] = lines
+			} else {
+				output[formula.Name] = fmt.Sprintf("%s %s%s%s\n  %s %s\n  %s %s",
+					green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()),
+					cyan("cache:"), filepath.Join(cfg.CacheDir, pkg.Name, pkg.FullVersion()),
+					cyan("path:"), filepath.Join(cfg.PackagesDir, pkg.Name, pkg.FullVersion()))
 			}
+			outMu.Unlock()
+			return nil
+		})
+	}
+	_ = ig.Wait()
 
-			if len(errs) > 0 {
-				for _, e := range errs {
-					fmt.Printf("%s %s\n", red("✗"), e)
+	for root, deps := range rootDeps {
+		if len(deps) > 0 {
+			mgr.SetDependencies(root, deps)
+		}
+	}
+
+	if err := mgr.Flush(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if opts.lockFile != "" {
+		installed, err := mgr.ListInstalled()
+		if err != nil {
+			return err
+		}
+		if err := updateLock(opts.lockFile, plan, results, installed); err != nil {
+			return fmt.Errorf("failed to update %s: %w", opts.lockFile, err)
+		}
+	}
+
+	if jsonOutput {
+		var out []packageResult
+		for _, rp := range plan {
+			if r, ok := results[rp.Formula.Name]; ok {
+				if deps, ok := rootDeps[r.Name]; ok && r.Status == statusInstalled {
+					r.Dependencies = deps
 				}
-				return fmt.Errorf("failed to install %d package(s)\n", len(errs))
+				out = append(out, r)
 			}
+		}
+		out = append(out, ip.failures...)
+		if err := printJSON(newPackagesResult(out)); err != nil {
+			return err
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("failed to install %d package(s)", len(errs))
+		}
+		return nil
+	}
 
-			return nil
-		},
+	fmt.Println()
+	for _, rp := range plan {
+		if msg, ok := output[rp.Formula.Name]; ok {
+			fmt.Println(msg)
+		}
 	}
 
-	cmd.Flags().StringVar(&sha256, "sha256", "", "Expected SHA256 checksum")
-	cmd.Flags().BoolVar(&cask, "cask", false, "Install a cask (macOS application)")
-	return cmd
+	if opts.lockFile != "" {
+		fmt.Printf("\n%s Updated %s\n", green("✓"), opts.lockFile)
+	}
+
+	if len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Printf("%s %s\n", red("✗"), e)
+		}
+		return fmt.Errorf("failed to install %d package(s)\n", len(errs))
+	}
+
+	return nil
+}
+
+// updateLock writes every package of plan that ended up installed to the
+// lockfile at path, keeping the entries of other packages. Packages that
+// were already installed at another version than the index has are not
+// locked, the lock would name an artifact that is not installed.
+func updateLock(path string, plan []resolver.ResolvedPackage, results map[string]packageResult, installed map[string]*domain.InstalledPackage) error {
+	l, err := lock.Load(path)
+	if os.IsNotExist(err) {
+		l, err = lock.New(), nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Plans list dependencies first, so a package whose dependency failed
+	// is left out rather than locked with a dangling reference
+	var stale []string
+	for _, rp := range plan {
+		switch results[rp.Formula.Name].Status {
+		case statusInstalled, statusUpgraded:
+		case statusAlreadyInstalled:
+			if pkg, ok := installed[rp.Formula.Name]; ok && pkg.FullVersion() != rp.Formula.FullVersion() {
+				stale = append(stale, fmt.Sprintf("%s %s (index has %s)", pkg.Name, pkg.FullVersion(), rp.Formula.FullVersion()))
+				continue
+			}
+		default:
+			continue
+		}
+		if !slices.ContainsFunc(rp.Formula.Dependencies, func(dep string) bool {
+			_, ok := l.Get(dep)
+			return !ok
+		}) {
+			l.Put(rp.Formula)
+		}
+	}
+	if err := l.Save(path); err != nil {
+		return err
+	}
+
+	if len(stale) > 0 {
+		return fmt.Errorf("not locked, installed at another version: %s, run chatr upgrade first", strings.Join(stale, ", "))
+	}
+	return nil
 }
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func newLeavesCmd() *cobra.Command {
+	var includeDeps bool
+
+	cmd := &cobra.Command{
+		Use:   "leaves",
+		Short: "List installed packages that no other package depends on",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, cfg, _, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			installed, err := mgr.ListInstalled()
+			if err != nil {
+				return err
+			}
+
+			required := make(map[string]bool)
+			for _, pkg := range installed {
+				for _, dep := range pkg.Dependencies {
+					if dep != pkg.Name {
+						required[dep] = true
+					}
+				}
+			}
+
+			var leaves []*domain.InstalledPackage
+			for name, pkg := range installed {
+				if required[name] {
+					continue
+				}
+				if pkg.IsDep && !includeDeps {
+					continue
+				}
+				leaves = append(leaves, pkg)
+			}
+
+			slices.SortFunc(leaves, func(a, b *domain.InstalledPackage) int {
+				return strings.Compare(a.Name, b.Name)
+			})
+
+			if jsonOutput {
+				results := make([]packageResult, 0, len(leaves))
+				for _, pkg := range leaves {
+					results = append(results, newPackageResult(cfg, pkg, statusInstalled))
+				}
+				return printJSON(newPackagesResult(results))
+			}
+
+			if len(leaves) == 0 {
+				fmt.Printf("%s No packages installed\n", dim("○"))
+				return nil
+			}
+
+			for _, pkg := range leaves {
+				line := fmt.Sprintf(" %s", bold(fmt.Sprintf("%s-%s", pkg.Name, pkg.FullVersion())))
+				if pkg.IsCask {
+					line += " " + dim("(cask)")
+				}
+				if pkg.IsDep {
+					line += " " + dim("(dependency)")
+				}
+				fmt.Println(line)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&includeDeps, "include-deps", false, "Include packages installed as dependencies")
+	return cmd
+}
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/spf13/cobra"
@@ -9,6 +11,22 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+type listedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Latest  string `json:"latest,omitempty"`
+	IsCask  bool   `json:"is_cask,omitempty"`
+	Pinned  bool   `json:"pinned,omitempty"`
+	Path    string `json:"path"`
+	// Others lists the installed versions that are not linked
+	Others []string `json:"other_versions,omitempty"`
+}
+
+type listResult struct {
+	Packages          []listedPackage `json:"packages"`
+	RemovedExternally []string        `json:"removed_externally,omitempty"`
+}
+
 func newListCmd() *cobra.Command {
 	var cask bool
 
@@ -21,9 +39,12 @@ func newListCmd() *cobra.Command {
 				return err
 			}
 
-			if removed := mgr.Reconcile(); len(removed) > 0 {
-				for _, name := range removed {
-					fmt.Printf("%s %s removed externally\n", dim("○"), name)
+			removed := mgr.Reconcile()
+			if len(removed) > 0 {
+				if !jsonOutput {
+					for _, name := range removed {
+						fmt.Printf("%s %s removed externally\n", dim("○"), name)
+					}
 				}
 				mgr.Flush()
 			}
@@ -47,6 +68,10 @@ func newListCmd() *cobra.Command {
 				packages = append(packages, pkg)
 			}
 
+			if len(packages) == 0 && jsonOutput {
+				return printJSON(listResult{Packages: []listedPackage{}, RemovedExternally: removed})
+			}
+
 			if len(packages) == 0 {
 				label := "packages"
 				if cask {
@@ -77,6 +102,38 @@ func newListCmd() *cobra.Command {
 			}
 			_ = g.Wait()
 
+			others := make(map[string][]string)
+			for _, pkg := range packages {
+				versions, err := mgr.Versions(pkg.Name)
+				if err != nil {
+					return err
+				}
+				for _, v := range versions {
+					if !v.Active {
+						others[pkg.Name] = append(others[pkg.Name], v.FullVersion())
+					}
+				}
+			}
+
+			if jsonOutput {
+				slices.SortFunc(packages, func(a, b *domain.InstalledPackage) int {
+					return strings.Compare(a.Name, b.Name)
+				})
+				out := listResult{Packages: make([]listedPackage, 0, len(packages)), RemovedExternally: removed}
+				for _, pkg := range packages {
+					out.Packages = append(out.Packages, listedPackage{
+						Name:    pkg.Name,
+						Version: pkg.FullVersion(),
+						Latest:  latest[pkg.Name],
+						IsCask:  pkg.IsCask,
+						Pinned:  pkg.Pinned,
+						Path:    pkg.Path,
+						Others:  others[pkg.Name],
+					})
+				}
+				return printJSON(out)
+			}
+
 			label := "Installed packages:"
 			if cask {
 				label = "Installed casks:"
@@ -88,6 +145,12 @@ func newListCmd() *cobra.Command {
 				if ver, ok := latest[pkg.Name]; ok && ver != pkg.Version {
 					line += fmt.Sprintf("  %s", yellow(fmt.Sprintf("↑ %s", ver)))
 				}
+				if pkg.Pinned {
+					line += fmt.Sprintf("  %s", cyan("(pinned)"))
+				}
+				if len(others[pkg.Name]) > 0 {
+					line += fmt.Sprintf("  %s", dim("(also "+strings.Join(others[pkg.Name], ", ")+")"))
+				}
 				fmt.Println(line)
 			}
 
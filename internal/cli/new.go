@@ -12,6 +12,10 @@ func newNewCommand() *cobra.Command {
 		Use:   "new",
 		Short: "Update chatr to the newest version",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("new does not support --json")
+			}
+
 			stop := withSpinner(cmd.Context(), "Updating chatr...")
 			c := exec.Command("sh", "-c", "curl -sL https://raw.githubusercontent.com/teamcutter/chatr/main/install.sh | sh")
 			err := c.Run()
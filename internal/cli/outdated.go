@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/registry"
+	"golang.org/x/sync/errgroup"
+)
+
+type outdatedPackage struct {
+	Name             string `json:"name"`
+	InstalledVersion string `json:"installed_version"`
+	LatestVersion    string `json:"latest_version"`
+	IsDep            bool   `json:"is_dep"`
+	IsCask           bool   `json:"is_cask"`
+}
+
+// outdatedFailure is an installed package the registry lookup failed
+// for, so whether it is outdated is unknown.
+type outdatedFailure struct {
+	Name             string `json:"name"`
+	InstalledVersion string `json:"installed_version"`
+	IsDep            bool   `json:"is_dep"`
+	IsCask           bool   `json:"is_cask"`
+	Error            string `json:"error"`
+}
+
+type outdatedResult struct {
+	Outdated []outdatedPackage `json:"outdated"`
+	Failures []outdatedFailure `json:"failures"`
+}
+
+func newOutdatedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "outdated",
+		Short:        "List installed packages with newer versions available",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, cfg, reg, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+			caskReg := registry.NewCask(cfg.FormulaeDir)
+
+			installed, err := mgr.ListInstalled()
+			if err != nil {
+				return err
+			}
+
+			outdated, failures, err := findOutdated(cmd.Context(), installed, reg, caskReg, cfg.MaxParallel)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(outdatedResult{Outdated: outdated, Failures: failures}); err != nil {
+					return err
+				}
+			} else if len(outdated) == 0 && len(failures) == 0 {
+				fmt.Printf("%s All packages are up-to-date\n", dim("○"))
+			} else {
+				for _, p := range outdated {
+					line := fmt.Sprintf(" %s  %s",
+						bold(fmt.Sprintf("%s-%s", p.Name, p.InstalledVersion)),
+						yellow(fmt.Sprintf("↑ %s", p.LatestVersion)))
+					if p.IsCask {
+						line += " " + dim("(cask)")
+					}
+					if p.IsDep {
+						line += " " + dim("(dependency)")
+					}
+					fmt.Println(line)
+				}
+				for _, f := range failures {
+					fmt.Printf(" %s %s: %s\n", red("✗"), bold(fmt.Sprintf("%s-%s", f.Name, f.InstalledVersion)), f.Error)
+				}
+			}
+
+			cmd.SilenceErrors = jsonOutput
+			switch {
+			case len(failures) > 0 && len(outdated) > 0:
+				return fmt.Errorf("%d package(s) outdated, failed to check %d", len(outdated), len(failures))
+			case len(failures) > 0:
+				return fmt.Errorf("failed to check %d package(s)", len(failures))
+			case len(outdated) > 0:
+				return fmt.Errorf("%d package(s) outdated", len(outdated))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// findOutdated compares the full version (including revision) of every
+// installed package, dependencies included, with the registry. Packages
+// the registry lookup failed for are returned as failures.
+func findOutdated(ctx context.Context, installed map[string]*domain.InstalledPackage, reg, caskReg domain.Registry, maxParallel int) ([]outdatedPackage, []outdatedFailure, error) {
+	outdated := []outdatedPackage{}
+	failures := []outdatedFailure{}
+	mu := &sync.Mutex{}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxParallel)
+
+	for _, pkg := range installed {
+		g.Go(func() error {
+			r := reg
+			if pkg.IsCask {
+				r = caskReg
+			}
+
+			formula, err := r.Get(gctx, pkg.Name)
+			if err != nil {
+				mu.Lock()
+				failures = append(failures, outdatedFailure{
+					Name:             pkg.Name,
+					InstalledVersion: pkg.FullVersion(),
+					IsDep:            pkg.IsDep,
+					IsCask:           pkg.IsCask,
+					Error:            err.Error(),
+				})
+				mu.Unlock()
+				return nil
+			}
+
+			if formula.FullVersion() == pkg.FullVersion() {
+				return nil
+			}
+
+			mu.Lock()
+			outdated = append(outdated, outdatedPackage{
+				Name:             pkg.Name,
+				InstalledVersion: pkg.FullVersion(),
+				LatestVersion:    formula.FullVersion(),
+				IsDep:            pkg.IsDep,
+				IsCask:           pkg.IsCask,
+			})
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	slices.SortFunc(outdated, func(a, b outdatedPackage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(failures, func(a, b outdatedFailure) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return outdated, failures, nil
+}
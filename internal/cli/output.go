@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/teamcutter/chatr/internal/config"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// jsonOutput is set by the persistent --json flag. Commands that support
+// it print a single JSON document to stdout instead of colored text.
+var jsonOutput bool
+
+const (
+	statusInstalled        = "installed"
+	statusAlreadyInstalled = "already_installed"
+	statusUpgraded         = "upgraded"
+	statusReinstalled      = "reinstalled"
+	statusFetched          = "fetched"
+	statusAlreadyCached    = "already_cached"
+	statusSwitched         = "switched"
+	statusUpToDate         = "up_to_date"
+	statusPinned           = "pinned"
+	statusUnpinned         = "unpinned"
+	statusRemoved          = "removed"
+	statusOrphaned         = "orphaned"
+	statusSkipped          = "skipped"
+	statusFailed           = "failed"
+)
+
+type packageResult struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version,omitempty"`
+	OldVersion   string   `json:"old_version,omitempty"`
+	Status       string   `json:"status"`
+	IsDep        bool     `json:"is_dep,omitempty"`
+	IsCask       bool     `json:"is_cask,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	Cache        string   `json:"cache,omitempty"`
+	Binaries     []string `json:"binaries,omitempty"`
+	Libs         []string `json:"libs,omitempty"`
+	Apps         []string `json:"apps,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+type packagesResult struct {
+	Packages []packageResult `json:"packages"`
+	Failed   int             `json:"failed"`
+}
+
+func newPackageResult(cfg *config.Config, pkg *domain.InstalledPackage, status string) packageResult {
+	res := packageResult{
+		Name:         pkg.Name,
+		Version:      pkg.FullVersion(),
+		Status:       status,
+		IsDep:        pkg.IsDep,
+		IsCask:       pkg.IsCask,
+		Dependencies: pkg.Dependencies,
+	}
+
+	if pkg.IsCask {
+		for _, app := range pkg.Apps {
+			res.Apps = append(res.Apps, filepath.Join(cfg.AppsDir, app))
+		}
+		return res
+	}
+
+	res.Path = pkg.Path
+	res.Cache = filepath.Join(cfg.CacheDir, pkg.Name, pkg.FullVersion())
+	for _, bin := range pkg.Binaries {
+		res.Binaries = append(res.Binaries, filepath.Join(cfg.BinDir, bin))
+	}
+	for _, lib := range pkg.Libs {
+		res.Libs = append(res.Libs, filepath.Join(cfg.LibDir, lib))
+	}
+	return res
+}
+
+func failedResult(name string, err error) packageResult {
+	return packageResult{Name: name, Status: statusFailed, Error: err.Error()}
+}
+
+func newPackagesResult(results []packageResult) packagesResult {
+	out := packagesResult{Packages: results}
+	if out.Packages == nil {
+		out.Packages = []packageResult{}
+	}
+	for _, r := range results {
+		if r.Status == statusFailed {
+			out.Failed++
+		}
+	}
+	return out
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
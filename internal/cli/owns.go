@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+)
+
+type ownsResult struct {
+	Path      string   `json:"path"`
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	IsCask    bool     `json:"is_cask,omitempty"`
+	Installed bool     `json:"installed"`
+	Active    bool     `json:"active"`
+	Projects  []string `json:"projects,omitempty"`
+}
+
+func newOwnsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "owns <path>",
+		Short: "Show which package a file belongs to",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, _, _, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+
+			pkg, err := mgr.Owner(path)
+			if err != nil {
+				return err
+			}
+			if pkg == nil {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("no installed package owns %s", path)
+			}
+
+			installed, projects, err := ownerUse(mgr, pkg)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(ownsResult{
+					Path:      path,
+					Name:      pkg.Name,
+					Version:   pkg.FullVersion(),
+					IsCask:    pkg.IsCask,
+					Installed: installed,
+					Active:    pkg.Active,
+					Projects:  projects,
+				})
+			}
+
+			label := bold(pkg.Name+"-"+pkg.FullVersion()) + ownerLabel(pkg, installed, projects)
+			fmt.Printf("%s is owned by %s\n", path, label)
+			return nil
+		},
+	}
+}
+
+// ownerUse reports whether a package version returned by Owner or
+// BinaryOwners is installed, and the projects that use it.
+func ownerUse(mgr *manager.Manager, pkg *domain.InstalledPackage) (installed bool, projects []string, err error) {
+	versions, err := mgr.Versions(pkg.Name)
+	if err != nil {
+		return false, nil, err
+	}
+	installed = slices.ContainsFunc(versions, func(v *domain.InstalledPackage) bool {
+		return v.FullVersion() == pkg.FullVersion()
+	})
+
+	projects, err = mgr.ProjectsUsing(pkg.Name, pkg.FullVersion())
+	return installed, projects, err
+}
+
+// ownerLabel describes a package version that is not simply the active
+// installed one: a cask, a version kept next to the active one, or one
+// provisioned for projects.
+func ownerLabel(pkg *domain.InstalledPackage, installed bool, projects []string) string {
+	var label string
+	if pkg.IsCask {
+		label += " " + dim("(cask)")
+	}
+	if installed && !pkg.Active {
+		label += " " + dim("(inactive)")
+	}
+	if len(projects) > 0 {
+		label += " " + dim("(project "+strings.Join(projects, ", ")+")")
+	}
+	return label
+}
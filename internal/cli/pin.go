@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newPinCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "pin <name>...",
+		Short:             "Pin packages so upgrade --all skips them",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeInstalled(true),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setPinned(args, true)
+		},
+	}
+}
+
+func newUnpinCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "unpin <name>...",
+		Short:             "Unpin packages so they can be upgraded again",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeInstalled(true),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setPinned(args, false)
+		},
+	}
+}
+
+func setPinned(names []string, pinned bool) error {
+	mgr, cfg, _, _, err := newManager()
+	if err != nil {
+		return err
+	}
+
+	action := statusPinned
+	if !pinned {
+		action = statusUnpinned
+	}
+
+	var failed int
+	var results []packageResult
+	for _, name := range names {
+		if err := mgr.SetPinned(name, pinned); err != nil {
+			results = append(results, failedResult(name, err))
+			if !jsonOutput {
+				fmt.Printf("%s %s: %v\n", red("✗"), name, err)
+			}
+			failed++
+			continue
+		}
+		if jsonOutput {
+			_, pkg, err := mgr.IsInstalled(name)
+			if err != nil {
+				return err
+			}
+			results = append(results, newPackageResult(cfg, pkg, action))
+			continue
+		}
+		fmt.Printf("%s %s %s\n", green("✓"), bold(name), action)
+	}
+
+	if err := mgr.Flush(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if jsonOutput {
+		if err := printJSON(newPackagesResult(results)); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d package(s)", failed)
+	}
+	return nil
+}
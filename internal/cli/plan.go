@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+	"github.com/teamcutter/chatr/internal/resolver"
+	"golang.org/x/sync/errgroup"
+)
+
+type installPlan struct {
+	// packages lists every package once, dependencies before dependents
+	packages []resolver.ResolvedPackage
+	rootDeps map[string][]string
+	errs     []error
+	failures []packageResult
+}
+
+// resolveInstallPlan resolves names in parallel and merges the results
+// into the plan install executes and install --dry-run prints.
+func resolveInstallPlan(ctx context.Context, res *resolver.Resolver, names []string, maxParallel int) installPlan {
+	mu := &sync.Mutex{}
+	ip := installPlan{rootDeps: make(map[string][]string)}
+
+	resolved := make([][]resolver.ResolvedPackage, len(names))
+
+	rg, rctx := errgroup.WithContext(ctx)
+	rg.SetLimit(min(len(names), maxParallel))
+
+	for i, name := range names {
+		rg.Go(func() error {
+			pkgs, err := resolve(rctx, res, name)
+			if err != nil {
+				mu.Lock()
+				ip.errs = append(ip.errs, fmt.Errorf("%s: %v", name, err))
+				ip.failures = append(ip.failures, failedResult(name, err))
+				mu.Unlock()
+				return nil
+			}
+			resolved[i] = pkgs
+			return nil
+		})
+	}
+	_ = rg.Wait()
+
+	seen := make(map[string]bool)
+
+	for _, pkgs := range resolved {
+		if len(pkgs) == 0 {
+			continue
+		}
+
+		rootName := pkgs[len(pkgs)-1].Formula.Name
+
+		for _, rp := range pkgs {
+			name := rp.Formula.Name
+			if rp.IsDep || rp.AlreadyInstalled {
+				ip.rootDeps[rootName] = append(ip.rootDeps[rootName], name)
+			}
+			if !seen[name] {
+				seen[name] = true
+				ip.packages = append(ip.packages, rp)
+			}
+		}
+	}
+
+	return ip
+}
+
+// resolve resolves name behind a spinner and reports the resolve phase
+// to the progress sink.
+func resolve(ctx context.Context, res *resolver.Resolver, name string) ([]resolver.ResolvedPackage, error) {
+	started := time.Now()
+	emit(domain.Event{Package: name, Phase: domain.PhaseResolve})
+
+	stop := withSpinner(ctx, fmt.Sprintf("Resolving %s...", name))
+	pkgs, err := res.Resolve(ctx, name)
+	stop()
+
+	emit(domain.Event{Package: name, Phase: domain.PhaseResolve, Duration: time.Since(started), Done: true, Err: err})
+	return pkgs, err
+}
+
+// toPackage converts a resolved formula into the package handed to the
+// manager. sha256 overrides the registry checksum of root packages.
+func toPackage(rp resolver.ResolvedPackage, sha256 string) domain.Package {
+	formula := rp.Formula
+
+	checksum := formula.SHA256
+	if !rp.IsDep && sha256 != "" {
+		checksum = sha256
+	}
+
+	return domain.Package{
+		Name:        formula.Name,
+		Version:     formula.Version,
+		Revision:    formula.Revision,
+		FullVersion: formula.FullVersion(),
+		DownloadURL: formula.URL,
+		SHA256:      checksum,
+		IsDep:       rp.IsDep,
+		IsCask:      formula.IsCask,
+	}
+}
+
+type dryRunResult struct {
+	Packages []*manager.PlannedPackage `json:"packages"`
+	Failures []packageResult           `json:"failures,omitempty"`
+}
+
+// planPackages plans pkgs in parallel. When upgrade is set the last
+// package is planned as an upgrade of the installed version.
+func planPackages(ctx context.Context, mgr *manager.Manager, pkgs []domain.Package, upgrade bool) ([]*manager.PlannedPackage, error) {
+	plans := make([]*manager.PlannedPackage, len(pkgs))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, pkg := range pkgs {
+		g.Go(func() error {
+			plan, err := mgr.Plan(gctx, pkg, upgrade && i == len(pkgs)-1)
+			if err != nil {
+				return fmt.Errorf("%s: %w", pkg.Name, err)
+			}
+			plans[i] = plan
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func printInstallPlan(ctx context.Context, mgr *manager.Manager, plan []resolver.ResolvedPackage, checksums map[string]string, failures []packageResult) error {
+	pkgs := make([]domain.Package, 0, len(plan))
+	for _, rp := range plan {
+		pkgs = append(pkgs, toPackage(rp, checksums[rp.Formula.Name]))
+	}
+
+	stop := withSpinner(ctx, "Planning...")
+	plans, err := planPackages(ctx, mgr, pkgs, false)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		if err := printJSON(dryRunResult{Packages: plans, Failures: failures}); err != nil {
+			return err
+		}
+	} else {
+		printPlans(plans)
+		for _, f := range failures {
+			fmt.Printf("%s %s: %s\n", red("✗"), f.Name, f.Error)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to resolve %d package(s)", len(failures))
+	}
+	return nil
+}
+
+func printPlans(plans []*manager.PlannedPackage) {
+	var download, cached, installed []*manager.PlannedPackage
+	for _, p := range plans {
+		switch {
+		case p.Action == manager.PlanSkip:
+			installed = append(installed, p)
+		case p.Cached:
+			cached = append(cached, p)
+		default:
+			download = append(download, p)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%s Dry run, nothing will be changed\n", dim("○"))
+
+	if len(download) > 0 {
+		var total int64
+		for _, p := range download {
+			total += max(p.Size, 0)
+		}
+		fmt.Printf("\n%s (%s)\n", bold("To download:"), formatSize(total))
+		for _, p := range download {
+			size := "size unknown"
+			if p.Size >= 0 {
+				size = formatSize(p.Size)
+			}
+			fmt.Printf("  %s %s\n", planLabel(p), dim("("+size+")"))
+		}
+	}
+
+	if len(cached) > 0 {
+		fmt.Printf("\n%s\n", bold("Already cached:"))
+		for _, p := range cached {
+			fmt.Printf("  %s\n", planLabel(p))
+		}
+	}
+
+	if len(installed) > 0 {
+		fmt.Printf("\n%s\n", bold("Already installed:"))
+		for _, p := range installed {
+			fmt.Printf("  %s\n", planLabel(p))
+		}
+	}
+
+	var links []manager.PlannedLink
+	var unknown []*manager.PlannedPackage
+	for _, p := range append(download, cached...) {
+		if !p.IsCask && !p.LinksKnown {
+			unknown = append(unknown, p)
+		}
+		links = append(links, p.Links...)
+	}
+
+	if len(links) > 0 || len(unknown) > 0 {
+		fmt.Printf("\n%s\n", bold("Links:"))
+		for _, l := range links {
+			if l.Replaces != "" {
+				fmt.Printf("  %s %s → %s %s\n", yellow("~"), l.Path, l.Target, dim("(replaces "+l.Replaces+")"))
+			} else {
+				fmt.Printf("  %s %s → %s\n", green("+"), l.Path, l.Target)
+			}
+		}
+		for _, p := range unknown {
+			reason := "links unknown until the download"
+			if p.Cached {
+				reason = "links unknown, the cached archive cannot be listed"
+			}
+			fmt.Printf("  %s %s %s\n", dim("?"), p.Name, dim("("+reason+")"))
+		}
+	}
+}
+
+func planLabel(p *manager.PlannedPackage) string {
+	label := bold(p.Name + "-" + p.Version)
+	switch p.Action {
+	case manager.PlanSkip:
+		label = bold(p.Name + "-" + p.InstalledVersion)
+	case manager.PlanUpgrade:
+		label = fmt.Sprintf("%s → %s", bold(p.Name+"-"+p.InstalledVersion), bold(p.Version))
+	}
+	if p.IsCask {
+		label += " " + dim("(cask)")
+	}
+	if p.IsDep {
+		label += " " + dim("(dependency)")
+	}
+	return label
+}
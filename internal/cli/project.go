@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+	"github.com/teamcutter/chatr/internal/project"
+	"github.com/teamcutter/chatr/internal/resolver"
+	"golang.org/x/sync/errgroup"
+)
+
+type projectTool struct {
+	Name     string   `json:"name"`
+	Wanted   string   `json:"wanted"`
+	Version  string   `json:"version,omitempty"`
+	Binaries []string `json:"binaries,omitempty"`
+}
+
+func newProjectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "project",
+		Short: "Manage the toolchain declared in .chatr.toml",
+		Long: `Manage the toolchain declared in the .chatr.toml of the current
+directory or its closest parent:
+
+  [tools]
+  terraform = "1.5.7"
+  protoc = "latest"
+
+Packages are shared with the global install in the packages directory,
+but each project links its tools into its own .chatr/bin. Tools with
+dependencies get a wrapper script there that puts the lib directories of
+the project's dependency versions on the library path.`,
+	}
+
+	cmd.AddCommand(
+		newProjectInstallCmd(),
+		newProjectListCmd(),
+	)
+	return cmd
+}
+
+func newProjectInstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install",
+		Short: "Install the project tools and link them into .chatr/bin",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return fmt.Errorf("project install does not support --json, use project list --json")
+			}
+
+			p, err := project.Find(".")
+			if err != nil {
+				return err
+			}
+
+			mgr, cfg, _, res, err := newManager()
+			if err != nil {
+				return err
+			}
+
+			if filepath.Clean(p.BinDir()) == filepath.Clean(cfg.BinDir) {
+				return fmt.Errorf("the bin directory of %s is the global one, move %s", p.Root, project.FileName)
+			}
+
+			cmd.SilenceUsage = true
+			return installProject(cmd.Context(), mgr, cfg, res, p)
+		},
+	}
+}
+
+// satisfies reports whether fullVersion is the version a project wants.
+// A wanted version without a revision matches any revision of it, the
+// way a package version is written in .chatr.toml.
+func satisfies(wanted, fullVersion string) bool {
+	version, _ := domain.SplitVersion(fullVersion)
+	return wanted == project.Latest || wanted == fullVersion || wanted == version
+}
+
+func installProject(ctx context.Context, mgr *manager.Manager, cfg *config.Config, res *resolver.Resolver, p *project.Project) error {
+	names := p.Names()
+	ip := resolveInstallPlan(ctx, res, names, cfg.MaxParallel)
+	errs := ip.errs
+
+	// Tools pinned to a version the registry no longer has can only come
+	// from the cache. Their dependencies are still resolved from the
+	// registry entry of the current version, the index has nothing else
+	var warnings []string
+	pkgs := make([]domain.Package, 0, len(ip.packages))
+	depsOf := make(map[string][]string, len(ip.packages))
+	for _, rp := range ip.packages {
+		depsOf[rp.Formula.Name] = rp.Formula.Dependencies
+		pkg := toPackage(rp, "")
+		wanted, isTool := p.Tools[pkg.Name]
+		if isTool && !satisfies(wanted, pkg.FullVersion) {
+			if !mgr.IsCached(pkg.Name, wanted) {
+				errs = append(errs, fmt.Errorf("%s: %s wanted, registry has %s and it is not in the cache", pkg.Name, wanted, pkg.FullVersion))
+				continue
+			}
+			if len(rp.Formula.Dependencies) > 0 {
+				warnings = append(warnings, fmt.Sprintf("%s %s is taken from the cache with the dependencies of %s, which may not be what it needs",
+					pkg.Name, wanted, pkg.FullVersion))
+			}
+			cached, err := mgr.CachedPackage(pkg.Name, wanted)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", pkg.Name, err))
+				continue
+			}
+			pkg = cached
+		}
+		pkg.IsDep = !isTool
+		pkgs = append(pkgs, pkg)
+	}
+
+	byName := make(map[string]domain.Package, len(pkgs))
+	for _, pkg := range pkgs {
+		byName[pkg.Name] = pkg
+	}
+
+	mu := &sync.Mutex{}
+	paths := make(map[string]string)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.MaxParallel)
+	for _, pkg := range pkgs {
+		g.Go(func() error {
+			path, err := mgr.Provision(gctx, pkg)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", pkg.Name, err))
+				return nil
+			}
+			paths[pkg.Name] = path
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	fmt.Println()
+	var tools []manager.FarmTool
+	var provisioned []domain.Package
+	for _, pkg := range pkgs {
+		if _, ok := paths[pkg.Name]; !ok {
+			continue
+		}
+		provisioned = append(provisioned, pkg)
+		if pkg.IsDep {
+			fmt.Printf("  %s %s%s%s %s\n", dim("↳"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion), dim("(dependency)"))
+			continue
+		}
+		tools = append(tools, manager.FarmTool{
+			Path: paths[pkg.Name],
+			Deps: dependencyClosure(pkg.Name, depsOf, byName),
+		})
+		fmt.Printf("%s %s%s%s\n", green("✓"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion))
+	}
+
+	binaries, err := mgr.LinkFarm(p.BinDir(), tools)
+	if err != nil {
+		return fmt.Errorf("failed to link %s: %w", p.BinDir(), err)
+	}
+	// Keep the symlink farm out of the project's repository
+	os.WriteFile(filepath.Join(p.Dir(), ".gitignore"), []byte("*\n"), 0644)
+
+	// Versions are only released once every tool could be provisioned
+	deleted, err := mgr.SetProjectPackages(p.Root, provisioned, len(errs) == 0)
+	if err != nil {
+		return fmt.Errorf("failed to record project packages: %w", err)
+	}
+
+	fmt.Printf("\n%s Linked %d binaries into %s\n", green("✓"), len(binaries), p.BinDir())
+	for _, w := range warnings {
+		fmt.Printf("%s %s\n", yellow("!"), w)
+	}
+	if len(deleted) > 0 {
+		fmt.Printf("%s Removed versions no project uses anymore: %s\n", dim("○"), strings.Join(deleted, ", "))
+	}
+	if os.Getenv(projectBinEnv) != p.BinDir() {
+		fmt.Printf("%s Run %s or add %s to your shell config to use them\n",
+			dim("○"), cyan(`eval "$(chatr env)"`), cyan(`eval "$(chatr hook <shell>)"`))
+	}
+
+	if len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Printf("%s %s\n", red("✗"), e)
+		}
+		return fmt.Errorf("failed to install %d tool(s)", len(errs))
+	}
+	return nil
+}
+
+// dependencyClosure returns the packages name depends on, directly or
+// through other dependencies, as they are provisioned for the project.
+func dependencyClosure(name string, depsOf map[string][]string, pkgs map[string]domain.Package) []domain.Package {
+	var closure []domain.Package
+	seen := map[string]bool{name: true}
+	var visit func(n string)
+	visit = func(n string) {
+		for _, dep := range depsOf[n] {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			if pkg, ok := pkgs[dep]; ok {
+				closure = append(closure, pkg)
+			}
+			visit(dep)
+		}
+	}
+	visit(name)
+	return closure
+}
+
+func newProjectListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the project tools and the versions linked for them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := project.Find(".")
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Binary links and wrappers point into packagesDir/name/version
+			linked := make(map[string]*projectTool)
+			entries, _ := os.ReadDir(p.BinDir())
+			for _, e := range entries {
+				target, ok := manager.FarmTarget(filepath.Join(p.BinDir(), e.Name()))
+				if !ok {
+					continue
+				}
+				rel, err := filepath.Rel(cfg.PackagesDir, target)
+				if err != nil || strings.HasPrefix(rel, "..") {
+					continue
+				}
+				parts := strings.SplitN(rel, string(filepath.Separator), 3)
+				if len(parts) < 3 {
+					continue
+				}
+				tool, ok := linked[parts[0]]
+				if !ok {
+					tool = &projectTool{Version: parts[1]}
+					linked[parts[0]] = tool
+				}
+				tool.Binaries = append(tool.Binaries, e.Name())
+			}
+
+			tools := make([]projectTool, 0, len(p.Tools))
+			for _, name := range p.Names() {
+				tool := projectTool{Name: name, Wanted: p.Tools[name]}
+				if l, ok := linked[name]; ok {
+					tool.Version = l.Version
+					tool.Binaries = l.Binaries
+				}
+				tools = append(tools, tool)
+			}
+
+			if jsonOutput {
+				return printJSON(tools)
+			}
+
+			fmt.Printf("%s %s\n", bold("Project:"), p.Root)
+			for _, t := range tools {
+				switch {
+				case t.Version == "":
+					fmt.Printf(" %s %s %s\n", red("✗"), t.Name, dim("(not linked, run chatr project install)"))
+				case !satisfies(t.Wanted, t.Version):
+					fmt.Printf(" %s %s-%s %s\n", yellow("!"), t.Name, t.Version, dim("(wants "+t.Wanted+", run chatr project install)"))
+				default:
+					fmt.Printf(" %s %s-%s %s\n", green("✓"), t.Name, t.Version, dim(strings.Join(t.Binaries, ", ")))
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func TestDependencyClosure(t *testing.T) {
+	tests := []struct {
+		name   string
+		depsOf map[string][]string
+		// provisioned are the packages provisioned for the project
+		provisioned []string
+		want        []string
+	}{
+		{
+			name:        "no dependencies",
+			provisioned: []string{"jq"},
+		},
+		{
+			name:        "direct dependencies",
+			depsOf:      map[string][]string{"jq": {"oniguruma"}},
+			provisioned: []string{"jq", "oniguruma"},
+			want:        []string{"oniguruma"},
+		},
+		{
+			name: "dependencies of dependencies",
+			depsOf: map[string][]string{
+				"git":     {"libgit2", "pcre2"},
+				"libgit2": {"openssl"},
+			},
+			provisioned: []string{"git", "libgit2", "openssl", "pcre2"},
+			want:        []string{"libgit2", "openssl", "pcre2"},
+		},
+		{
+			name: "shared dependency listed once",
+			depsOf: map[string][]string{
+				"curl":    {"openssl", "libssh2"},
+				"libssh2": {"openssl"},
+			},
+			provisioned: []string{"curl", "libssh2", "openssl"},
+			want:        []string{"openssl", "libssh2"},
+		},
+		{
+			name: "cycle back to the package",
+			depsOf: map[string][]string{
+				"a": {"b"},
+				"b": {"a"},
+			},
+			provisioned: []string{"a", "b"},
+			want:        []string{"b"},
+		},
+		{
+			name: "dependencies that failed to provision are skipped",
+			depsOf: map[string][]string{
+				"git":     {"libgit2"},
+				"libgit2": {"openssl"},
+			},
+			provisioned: []string{"git", "openssl"},
+			want:        []string{"openssl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkgs := make(map[string]domain.Package)
+			for _, name := range tt.provisioned {
+				pkgs[name] = domain.Package{Name: name, FullVersion: "1.0"}
+			}
+
+			var got []string
+			for _, pkg := range dependencyClosure(tt.provisioned[0], tt.depsOf, pkgs) {
+				got = append(got, pkg.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("dependencyClosure(%s) = %q, want %q", tt.provisioned[0], got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		wanted      string
+		fullVersion string
+		want        bool
+	}{
+		{"latest", "1.7_1", true},
+		{"1.7", "1.7", true},
+		{"1.7", "1.7_1", true},
+		{"1.7_1", "1.7_1", true},
+		{"1.7_1", "1.7_2", false},
+		{"1.7_1", "1.7", false},
+		{"1.6", "1.7_1", false},
+		{"1.7", "1.7.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := satisfies(tt.wanted, tt.fullVersion); got != tt.want {
+			t.Errorf("satisfies(%q, %q) = %v, want %v", tt.wanted, tt.fullVersion, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/registry"
+	"golang.org/x/sync/errgroup"
+)
+
+func newReinstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "reinstall <name>...",
+		Short:             "Reinstall packages from the cache",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeInstalled(true),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, cfg, reg, _, err := newManager()
+			if err != nil {
+				return err
+			}
+			caskReg := registry.NewCask(cfg.FormulaeDir)
+
+			if err := mgr.Begin(commandLine()); err != nil {
+				return err
+			}
+			defer mgr.Commit()
+
+			g, ctx := errgroup.WithContext(cmd.Context())
+			g.SetLimit(min(len(args), cfg.MaxParallel))
+
+			mu := &sync.Mutex{}
+			var errs []error
+			output := make(map[string]string)
+			results := make(map[string]packageResult)
+
+			for _, name := range args {
+				g.Go(func() error {
+					_, installedPkg, err := mgr.IsInstalled(name)
+					if err != nil || installedPkg == nil {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("%s: not installed", name))
+						results[name] = failedResult(name, fmt.Errorf("not installed"))
+						mu.Unlock()
+						return nil
+					}
+
+					pkg := domain.Package{
+						Name:        installedPkg.Name,
+						Version:     installedPkg.Version,
+						Revision:    installedPkg.Revision,
+						FullVersion: installedPkg.FullVersion(),
+						DownloadURL: installedPkg.URL,
+						IsDep:       installedPkg.IsDep,
+						IsCask:      installedPkg.IsCask,
+					}
+
+					// The checksum is not kept in state, so take it from the
+					// registry when the archive has to be downloaded again.
+					// Without one the download could not be verified.
+					if !mgr.IsCached(pkg.Name, pkg.FullVersion) {
+						r := reg
+						if pkg.IsCask {
+							r = caskReg
+						}
+						formula, err := r.Get(ctx, pkg.Name)
+						switch {
+						case err != nil:
+							err = fmt.Errorf("not cached, and looking up its checksum failed: %v", err)
+						case formula.FullVersion() != pkg.FullVersion:
+							err = fmt.Errorf("not cached, and the registry has moved on to %s, so a download could not be verified", formula.FullVersion())
+						case formula.SHA256 == "":
+							err = fmt.Errorf("not cached, and the registry has no checksum to verify a download")
+						default:
+							pkg.SHA256 = formula.SHA256
+						}
+						if err != nil {
+							mu.Lock()
+							errs = append(errs, fmt.Errorf("%s: %v", name, err))
+							results[name] = failedResult(name, err)
+							mu.Unlock()
+							return nil
+						}
+					}
+
+					reinstalled, err := mgr.Reinstall(ctx, pkg)
+					if err != nil {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("%s: %v", name, err))
+						results[name] = failedResult(name, err)
+						mu.Unlock()
+						return nil
+					}
+
+					mu.Lock()
+					results[name] = newPackageResult(cfg, reinstalled, statusReinstalled)
+					output[name] = fmt.Sprintf("%s %s%s%s reinstalled",
+						green("✓"), bold(reinstalled.Name), bold("-"), bold(reinstalled.FullVersion()))
+					mu.Unlock()
+					return nil
+				})
+			}
+			_ = g.Wait()
+
+			if err := mgr.Flush(); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+
+			if jsonOutput {
+				ordered := make([]packageResult, 0, len(args))
+				for _, name := range args {
+					ordered = append(ordered, results[name])
+				}
+				if err := printJSON(newPackagesResult(ordered)); err != nil {
+					return err
+				}
+				if len(errs) > 0 {
+					return fmt.Errorf("failed to reinstall %d package(s)", len(errs))
+				}
+				return nil
+			}
+
+			fmt.Println()
+			for _, name := range args {
+				if msg, ok := output[name]; ok {
+					fmt.Println(msg)
+				}
+			}
+
+			if len(errs) > 0 {
+				for _, e := range errs {
+					fmt.Printf("%s %s\n", red("✗"), e)
+				}
+				return fmt.Errorf("failed to reinstall %d package(s)", len(errs))
+			}
+
+			return nil
+		},
+	}
+}
@@ -4,16 +4,20 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
 	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
 )
 
 func newRemoveCmd() *cobra.Command {
 	var version string
 	var all bool
+	var dryRun bool
 
 	cmd := &cobra.Command{
-		Use:   "remove [name...]",
-		Short: "Remove installed packages",
+		Use:               "remove [name...]",
+		Short:             "Remove installed packages",
+		ValidArgsFunction: completeInstalled(true),
 		Args: func(cmd *cobra.Command, args []string) error {
 			if all {
 				return nil
@@ -21,7 +25,14 @@ func newRemoveCmd() *cobra.Command {
 			return cobra.MinimumNArgs(1)(cmd, args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			mgr, _, _, _, _ := newManager()
+			open := newManagerWithOptions
+			if dryRun {
+				open = newReadOnlyManager
+			}
+			mgr, cfg, _, _, err := open(false)
+			if err != nil {
+				return err
+			}
 
 			packages := args
 			if all {
@@ -37,30 +48,62 @@ func newRemoveCmd() *cobra.Command {
 					packages = append(packages, name)
 				}
 				if len(packages) == 0 {
+					if jsonOutput {
+						return printJSON(newPackagesResult(nil))
+					}
 					fmt.Printf("%s No packages installed\n", dim("○"))
 					return nil
 				}
 			}
 
-			fmt.Println()
+			cmd.SilenceUsage = true
+			if dryRun {
+				return printRemovePlan(mgr, cfg, packages, version)
+			}
+
+			if err := mgr.Begin(commandLine()); err != nil {
+				return err
+			}
+			defer mgr.Commit()
+
+			if !jsonOutput {
+				fmt.Println()
+			}
 			var failed int
+			var results []packageResult
 			for _, arg := range packages {
 				removedPackage, err := mgr.Remove(cmd.Context(), domain.Package{
 					Name:    arg,
 					Version: version,
 				})
 				if err != nil {
-					fmt.Printf("%s %s: %v\n", red("✗"), arg, err)
+					results = append(results, failedResult(arg, err))
+					if !jsonOutput {
+						fmt.Printf("%s %s: %v\n", red("✗"), arg, err)
+					}
 					failed++
 					continue
 				}
-				fmt.Printf("%s %s%s%s removed (with %s dependencies)\n", green("✓"), bold(removedPackage.Name), bold("-"), bold(removedPackage.FullVersion()), green(len(removedPackage.Dependencies)))
+				results = append(results, newPackageResult(cfg, removedPackage, statusRemoved))
+				switch {
+				case jsonOutput:
+				case !removedPackage.Active:
+					fmt.Printf("%s %s%s%s removed\n", green("✓"), bold(removedPackage.Name), bold("-"), bold(removedPackage.FullVersion()))
+				default:
+					fmt.Printf("%s %s%s%s removed (with %s dependencies)\n", green("✓"), bold(removedPackage.Name), bold("-"), bold(removedPackage.FullVersion()), green(len(removedPackage.Dependencies)))
+				}
 			}
 
 			if err := mgr.Flush(); err != nil {
 				return fmt.Errorf("failed to save state: %w", err)
 			}
 
+			if jsonOutput {
+				if err := printJSON(newPackagesResult(results)); err != nil {
+					return err
+				}
+			}
+
 			if failed > 0 {
 				return fmt.Errorf("failed to remove %d package(s)", failed)
 			}
@@ -68,7 +111,121 @@ func newRemoveCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&version, "version", "v", "latest", "Package version")
+	cmd.Flags().StringVarP(&version, "version", "v", "", "Remove only this installed version")
 	cmd.Flags().BoolVar(&all, "all", false, "Remove all installed packages")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be removed without changing anything")
+	cmd.MarkFlagsMutuallyExclusive("version", "all")
 	return cmd
 }
+
+type removePlan struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Binaries     []string `json:"binaries,omitempty"`
+	Libs         []string `json:"libs,omitempty"`
+	Apps         []string `json:"apps,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+func printRemovePlan(mgr *manager.Manager, cfg *config.Config, names []string, version string) error {
+	var plans []removePlan
+	var failures []packageResult
+
+	for _, name := range names {
+		if version != "" {
+			inactive, err := planRemoveVersion(mgr, name, version)
+			if err != nil {
+				failures = append(failures, failedResult(name, err))
+				continue
+			}
+			if inactive != nil {
+				plans = append(plans, removePlan{Name: name, Version: inactive.FullVersion()})
+				continue
+			}
+		}
+
+		removed, err := mgr.PlanRemove(name)
+		if err == nil && len(removed) == 0 {
+			err = fmt.Errorf("package %s is not installed", name)
+		}
+		if err != nil {
+			failures = append(failures, failedResult(name, err))
+			continue
+		}
+
+		root := newPackageResult(cfg, removed[0], statusRemoved)
+		plan := removePlan{
+			Name:     root.Name,
+			Version:  root.Version,
+			Binaries: root.Binaries,
+			Libs:     root.Libs,
+			Apps:     root.Apps,
+		}
+		for _, dep := range removed[1:] {
+			plan.Dependencies = append(plan.Dependencies, dep.Name)
+		}
+		plans = append(plans, plan)
+	}
+
+	if jsonOutput {
+		out := struct {
+			Packages []removePlan    `json:"packages"`
+			Failures []packageResult `json:"failures,omitempty"`
+		}{plans, failures}
+		if out.Packages == nil {
+			out.Packages = []removePlan{}
+		}
+		if err := printJSON(out); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("\n%s Dry run, nothing will be changed\n\n", dim("○"))
+		for _, p := range plans {
+			fmt.Printf("%s %s%s%s\n", red("-"), bold(p.Name), bold("-"), bold(p.Version))
+			for _, bin := range p.Binaries {
+				fmt.Printf("  %s %s\n", cyan("bin:"), bin)
+			}
+			for _, lib := range p.Libs {
+				fmt.Printf("  %s %s\n", cyan("lib:"), lib)
+			}
+			for _, app := range p.Apps {
+				fmt.Printf("  %s %s\n", cyan("app:"), app)
+			}
+			for _, dep := range p.Dependencies {
+				fmt.Printf("  %s %s %s\n", dim("↳"), dep, dim("(unused dependency)"))
+			}
+		}
+		for _, f := range failures {
+			fmt.Printf("%s %s: %s\n", red("✗"), f.Name, f.Error)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to remove %d package(s)", len(failures))
+	}
+	return nil
+}
+
+// planRemoveVersion returns the version of name that remove --version
+// would take out on its own, or nil if it is the only installed version
+// and the package would be removed as a whole.
+func planRemoveVersion(mgr *manager.Manager, name, version string) (*domain.InstalledPackage, error) {
+	versions, err := mgr.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("package %s is not installed", name)
+	}
+	v, err := manager.MatchVersion(name, version, versions)
+	if err != nil {
+		return nil, err
+	}
+	if !v.Active {
+		return v, nil
+	}
+	if len(versions) > 1 {
+		return nil, fmt.Errorf("%s %s is the active version, switch to another one first", name, v.FullVersion())
+	}
+	return nil, nil
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
+)
+
+type rollbackResult struct {
+	Transactions []int64                `json:"transactions"`
+	Steps        []manager.RollbackStep `json:"steps"`
+	Failed       []packageResult        `json:"failed,omitempty"`
+}
+
+func newRollbackCmd() *cobra.Command {
+	var to int64
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Undo the last transaction, or every transaction after --to",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, _, _, _, err := newManager()
+			if err != nil {
+				return err
+			}
+
+			transactions, err := mgr.Transactions()
+			if err != nil {
+				return err
+			}
+
+			after, undone, err := rollbackTarget(transactions, to, cmd.Flags().Changed("to"))
+			if err != nil {
+				return err
+			}
+
+			steps, err := mgr.PlanRollback(after)
+			if err != nil {
+				return err
+			}
+
+			var ids []int64
+			for _, t := range undone {
+				ids = append(ids, t.ID)
+			}
+
+			if len(steps) == 0 {
+				if jsonOutput {
+					return printJSON(rollbackResult{Transactions: ids, Steps: []manager.RollbackStep{}})
+				}
+				fmt.Printf("%s Nothing to roll back\n", dim("○"))
+				return nil
+			}
+
+			var missing int
+			for _, step := range steps {
+				if !step.Cached {
+					missing++
+				}
+			}
+
+			if !jsonOutput {
+				for _, t := range undone {
+					fmt.Printf("%s %s %s\n", dim("↶"), dim(fmt.Sprintf("#%d", t.ID)), bold(t.Command))
+				}
+				fmt.Println()
+				for _, step := range steps {
+					fmt.Printf("  %s\n", formatRollbackStep(step))
+				}
+			}
+
+			if missing > 0 {
+				cmd.SilenceUsage = true
+				if jsonOutput {
+					printJSON(rollbackResult{Transactions: ids, Steps: steps})
+				} else {
+					fmt.Println()
+				}
+				return fmt.Errorf("cannot roll back, %d archive(s) no longer in the cache", missing)
+			}
+
+			if dryRun {
+				if jsonOutput {
+					return printJSON(rollbackResult{Transactions: ids, Steps: steps})
+				}
+				fmt.Printf("\n%s Dry run, nothing will be changed\n", dim("○"))
+				return nil
+			}
+
+			if err := mgr.Begin(commandLine()); err != nil {
+				return err
+			}
+			defer mgr.Commit()
+
+			if !jsonOutput {
+				fmt.Println()
+			}
+
+			var failed []packageResult
+			err = mgr.Rollback(cmd.Context(), steps, func(step manager.RollbackStep, err error) {
+				if err != nil {
+					failed = append(failed, failedResult(step.Name, err))
+					if !jsonOutput {
+						fmt.Printf("%s %s: %v\n", red("✗"), step.Name, err)
+					}
+					return
+				}
+				if jsonOutput {
+					return
+				}
+				if step.To == "" {
+					fmt.Printf("%s %s%s%s removed\n", green("✓"), bold(step.Name), bold("-"), bold(step.From))
+				} else {
+					fmt.Printf("%s %s%s%s restored\n", green("✓"), bold(step.Name), bold("-"), bold(step.To))
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := mgr.Flush(); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+
+			if jsonOutput {
+				if err := printJSON(rollbackResult{Transactions: ids, Steps: steps, Failed: failed}); err != nil {
+					return err
+				}
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to roll back %d package(s)", len(failed))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&to, "to", 0, "Undo every transaction after this history ID")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be restored without changing anything")
+	return cmd
+}
+
+// rollbackTarget returns the ID to roll back to and the transactions that
+// are undone, newest first. Without --to the latest transaction that
+// changed something is undone.
+func rollbackTarget(transactions []*domain.Transaction, to int64, toSet bool) (int64, []*domain.Transaction, error) {
+	if toSet {
+		var undone []*domain.Transaction
+		found := false
+		for _, t := range transactions {
+			if t.ID == to {
+				found = true
+			}
+			if t.ID > to {
+				undone = append(undone, t)
+			}
+		}
+		if !found {
+			return 0, nil, fmt.Errorf("transaction #%d not found, see chatr history", to)
+		}
+		return to, undone, nil
+	}
+
+	for _, t := range transactions {
+		for _, c := range t.Changes {
+			if c.Outcome == domain.OutcomeSuccess {
+				return t.ID - 1, []*domain.Transaction{t}, nil
+			}
+		}
+	}
+	return 0, nil, fmt.Errorf("no transaction to roll back")
+}
+
+func formatRollbackStep(step manager.RollbackStep) string {
+	var line string
+	switch {
+	case step.To == "":
+		line = fmt.Sprintf("%s %s %s", red("-"), step.Name, step.From)
+	case step.From == "":
+		line = fmt.Sprintf("%s %s %s", green("+"), step.Name, step.To)
+	default:
+		line = fmt.Sprintf("%s %s %s → %s", cyan("↶"), step.Name, step.From, step.To)
+	}
+	if step.IsCask {
+		line += " " + dim("(cask)")
+	}
+	if step.IsDep {
+		line += " " + dim("(dependency)")
+	}
+	if step.Inactive {
+		line += " " + dim("(inactive)")
+	}
+	if !step.Cached {
+		line += " " + red("(not in cache)")
+	}
+	return line
+}
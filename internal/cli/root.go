@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -10,22 +11,74 @@ import (
 	"github.com/teamcutter/chatr/internal/extractor"
 	"github.com/teamcutter/chatr/internal/fetcher"
 	"github.com/teamcutter/chatr/internal/manager"
+	"github.com/teamcutter/chatr/internal/progress"
 	"github.com/teamcutter/chatr/internal/registry"
 	"github.com/teamcutter/chatr/internal/resolver"
 	"github.com/teamcutter/chatr/internal/state"
 )
 
+// configFile is set by the persistent --config flag
+var configFile string
+
 func Execute() error {
-	rootCmd := &cobra.Command{Use: "chatr"}
+	rootCmd := &cobra.Command{
+		Use: "chatr",
+		// Replaced by newCompletionCmd
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+	}
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if configFile != "" {
+			config.SetPath(configFile)
+		}
+
+		// Failures are already reported in the JSON document
+		if jsonOutput {
+			rootCmd.SilenceUsage = true
+		}
+
+		sink, err := progress.New(progressMode, os.Stderr)
+		if err != nil {
+			return err
+		}
+		events = sink
+		return nil
+	}
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to use instead of ~/.chatr/config.toml")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON output")
+	rootCmd.PersistentFlags().StringVar(&progressMode, "progress", progress.ModeBar, "Progress output on stderr: bar, plain or json")
 	rootCmd.AddCommand(
 		newInstallCmd(),
+		newFetchCmd(),
 		newRemoveCmd(),
+		newReinstallCmd(),
+		newAutoremoveCmd(),
 		newListCmd(),
+		newOutdatedCmd(),
+		newLeavesCmd(),
 		newSearchCmd(),
+		newInfoCmd(),
+		newDepsCmd(),
+		newUsesCmd(),
+		newWhichCmd(),
+		newOwnsCmd(),
+		newHistoryCmd(),
+		newRollbackCmd(),
 		newClearCmd(),
+		newDoctorCmd(),
 		newVersionCmd(),
 		newNewCommand(),
 		newUpgradeCmd(),
+		newSwitchCmd(),
+		newPinCmd(),
+		newUnpinCmd(),
+		newBundleCmd(),
+		newExportCmd(),
+		newImportCmd(),
+		newProjectCmd(),
+		newEnvCmd(),
+		newHookCmd(),
+		newConfigCmd(),
+		newCompletionCmd(),
 	)
 	return rootCmd.Execute()
 }
@@ -35,6 +88,44 @@ func newManager() (*manager.Manager, *config.Config, domain.Registry, *resolver.
 }
 
 func newManagerWithOptions(cask bool) (*manager.Manager, *config.Config, domain.Registry, *resolver.Resolver, error) {
+	return newManagerWithRegistry(registryFor(cask))
+}
+
+// newManagerWithRegistry is newManager resolving against the registry
+// returned by newRegistry instead of the Homebrew index.
+func newManagerWithRegistry(newRegistry func(cfg *config.Config) domain.Registry) (*manager.Manager, *config.Config, domain.Registry, *resolver.Resolver, error) {
+	return openManager(newRegistry, stateFor(false))
+}
+
+// newReadOnlyManager is newManagerWithOptions for commands that only
+// look at what is installed. The state is opened read-only, so it is
+// not cleaned up after installs that may still be running in another
+// chatr. Only a database from an older chatr is migrated first.
+func newReadOnlyManager(cask bool) (*manager.Manager, *config.Config, domain.Registry, *resolver.Resolver, error) {
+	return openManager(registryFor(cask), stateFor(true))
+}
+
+// stateFor returns how openManager opens the state. Dry runs pass
+// readOnly so that planning never recovers or rewrites anything.
+func stateFor(readOnly bool) func(cfg *config.Config) (*state.SQLiteState, error) {
+	return func(cfg *config.Config) (*state.SQLiteState, error) {
+		if readOnly {
+			return state.OpenReadOnly(cfg.StateDB)
+		}
+		return state.NewSQLite(cfg.StateDB, cfg.ManifestFile)
+	}
+}
+
+func registryFor(cask bool) func(cfg *config.Config) domain.Registry {
+	return func(cfg *config.Config) domain.Registry {
+		if cask {
+			return registry.NewCask(cfg.FormulaeDir)
+		}
+		return registry.New(cfg.FormulaeDir)
+	}
+}
+
+func openManager(newRegistry func(cfg *config.Config) domain.Registry, openState func(cfg *config.Config) (*state.SQLiteState, error)) (*manager.Manager, *config.Config, domain.Registry, *resolver.Resolver, error) {
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, nil, nil, nil, err
@@ -45,23 +136,19 @@ func newManagerWithOptions(cask bool) (*manager.Manager, *config.Config, domain.
 		return nil, nil, nil, nil, err
 	}
 
-	var reg domain.Registry
-	if cask {
-		reg = registry.NewCask(cfg.FormulaeDir)
-	} else {
-		reg = registry.New(cfg.FormulaeDir)
-	}
+	reg := newRegistry(cfg)
 
-	st, err := state.NewSQLite(cfg.StateDB, cfg.ManifestFile)
+	st, err := openState(cfg)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
 
 	mgr := manager.New(
-		fetcher.New(cfg.CacheDir, 1*time.Hour),
+		fetcher.New(cfg.CacheDir, 1*time.Hour, events),
 		c,
 		extractor.New(),
 		st,
+		events,
 		cfg.PackagesDir,
 		cfg.BinDir,
 		cfg.LibDir,
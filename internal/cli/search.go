@@ -6,14 +6,29 @@ import (
 	"github.com/spf13/cobra"
 )
 
+type searchResult struct {
+	Query   string         `json:"query"`
+	Total   int            `json:"total"`
+	Results []searchedItem `json:"results"`
+}
+
+type searchedItem struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	Homepage    string `json:"homepage,omitempty"`
+	IsCask      bool   `json:"is_cask,omitempty"`
+}
+
 func newSearchCmd() *cobra.Command {
 	var show int
 	var cask bool
 
 	cmd := &cobra.Command{
-		Use:   "search <query>",
-		Short: "Search for packages",
-		Args:  cobra.ExactArgs(1),
+		Use:               "search <query>",
+		Short:             "Search for packages",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeIndex(false),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			_, _, reg, _, err := newManagerWithOptions(cask)
 			if err != nil {
@@ -27,13 +42,27 @@ func newSearchCmd() *cobra.Command {
 				return err
 			}
 
+			size := min(len(results), show)
+
+			if jsonOutput {
+				out := searchResult{Query: args[0], Total: len(results), Results: make([]searchedItem, 0, size)}
+				for _, r := range results[:size] {
+					out.Results = append(out.Results, searchedItem{
+						Name:        r.Name,
+						Version:     r.FullVersion(),
+						Description: r.Description,
+						Homepage:    r.Homepage,
+						IsCask:      r.IsCask,
+					})
+				}
+				return printJSON(out)
+			}
+
 			if len(results) == 0 {
 				fmt.Printf("%s No results found for %q\n", dim("○"), args[0])
 				return nil
 			}
 
-			size := min(len(results), show)
-
 			fmt.Printf("Showing %s of %s results for %q\n\n", green(size), green(len(results)), args[0])
 
 			for i := range size {
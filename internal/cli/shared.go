@@ -2,10 +2,14 @@ package cli
 
 import (
 	"context"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/schollz/progressbar/v3"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/progress"
 )
 
 var (
@@ -17,8 +21,39 @@ var (
 	yellow = color.New(color.FgYellow).SprintFunc()
 )
 
+var (
+	// progressMode is set by the persistent --progress flag
+	progressMode = progress.ModeBar
+	// events receives progress of every package operation, it is
+	// created from progressMode before any command runs
+	events domain.EventSink
+)
+
+func emit(e domain.Event) {
+	if events == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	events.Emit(e)
+}
+
+// commandLine returns the command being run as it is recorded in the
+// history.
+func commandLine() string {
+	return strings.Join(append([]string{"chatr"}, os.Args[1:]...), " ")
+}
+
 func withSpinner(ctx context.Context, desc string) (stop func()) {
+	// Spinners would interleave with the plain and JSON event streams
+	if jsonOutput || progressMode != progress.ModeBar {
+		return func() {}
+	}
+
+	// On stderr, so output redirected to a file stays clean
 	spinner := progressbar.NewOptions(-1,
+		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionSetDescription(desc),
 		progressbar.OptionSpinnerType(14),
 		progressbar.OptionClearOnFinish(),
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func newSwitchCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "switch <name> <version>",
+		Short: "Change which installed version of a package is linked",
+		Long: `Link another installed version of a package into the bin and lib
+directories. Versions are kept side by side by upgrade --keep. A version
+that is not installed but still in the cache is installed from there.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeVersions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, version := args[0], args[1]
+
+			mgr, cfg, _, _, err := newManager()
+			if err != nil {
+				return err
+			}
+
+			_, current, err := mgr.IsInstalled(name)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				return fmt.Errorf("package %s is not installed", name)
+			}
+			if current.IsCask {
+				return fmt.Errorf("cannot switch versions of cask %s", name)
+			}
+
+			cmd.SilenceUsage = true
+			if current.FullVersion() == version || current.Version == version {
+				if jsonOutput {
+					return printJSON(newPackageResult(cfg, current, statusUpToDate))
+				}
+				fmt.Printf("%s %s%s%s is already active\n", dim("○"), bold(name), bold("-"), bold(current.FullVersion()))
+				return nil
+			}
+
+			if err := mgr.Begin(commandLine()); err != nil {
+				return err
+			}
+			defer mgr.Commit()
+
+			versions, err := mgr.Versions(name)
+			if err != nil {
+				return err
+			}
+			installed := slices.ContainsFunc(versions, func(v *domain.InstalledPackage) bool {
+				return v.FullVersion() == version || v.Version == version
+			})
+
+			var pkg *domain.InstalledPackage
+			switch {
+			case installed:
+				pkg, err = mgr.Switch(name, version)
+			case mgr.IsCached(name, version):
+				var target domain.Package
+				if target, err = mgr.CachedPackage(name, version); err == nil {
+					target.IsDep = current.IsDep
+					pkg, err = mgr.UpgradeKeep(cmd.Context(),
+						domain.Package{Name: name, FullVersion: current.FullVersion()}, target)
+				}
+			default:
+				err = fmt.Errorf("%s %s is neither installed nor in the cache", name, version)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := mgr.Flush(); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+
+			if jsonOutput {
+				result := newPackageResult(cfg, pkg, statusSwitched)
+				result.OldVersion = current.FullVersion()
+				return printJSON(result)
+			}
+			fmt.Printf("%s %s%s%s → %s\n", green("✓"), bold(name), bold("-"), bold(current.FullVersion()), bold(pkg.FullVersion()))
+			return nil
+		},
+	}
+}
+
+// completeVersions completes installed package names, then the installed
+// versions of the chosen package.
+func completeVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeInstalled(false)(cmd, args, toComplete)
+	}
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	st, err := readState()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer st.Close()
+	versions, err := st.Versions(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, v := range versions {
+		if !v.Active {
+			names = append(names, v.FullVersion())
+		}
+	}
+	return filterCompletions(names, nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
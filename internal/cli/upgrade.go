@@ -1,22 +1,30 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/spf13/cobra"
 	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/manager"
 	"github.com/teamcutter/chatr/internal/resolver"
 	"golang.org/x/sync/errgroup"
 )
 
 func newUpgradeCmd() *cobra.Command {
 	var all bool
+	var force bool
+	var dryRun bool
+	var keep bool
 
 	cmd := &cobra.Command{
-		Use:   "upgrade [name...]",
-		Short: "Upgrade installed packages to latest version",
+		Use:               "upgrade [name...]",
+		Short:             "Upgrade installed packages to latest version",
+		ValidArgsFunction: completeInstalled(true),
 		Args: func(cmd *cobra.Command, args []string) error {
 			if all {
 				return nil
@@ -24,174 +32,318 @@ func newUpgradeCmd() *cobra.Command {
 			return cobra.MinimumNArgs(1)(cmd, args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			mgr, cfg, _, formulaRes, err := newManagerWithOptions(false)
-			if err != nil {
-				return err
+			return runUpgrade(cmd.Context(), args, upgradeOptions{all: all, force: force, dryRun: dryRun, keep: keep})
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Upgrade all installed packages")
+	cmd.Flags().BoolVar(&force, "force", false, "Upgrade the pinned packages named on the command line, --all still skips pinned ones")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be upgraded without changing anything")
+	cmd.Flags().BoolVar(&keep, "keep", false, "Keep the old versions installed so chatr switch can go back")
+	return cmd
+}
+
+type upgradeOptions struct {
+	all bool
+	// force upgrades pinned packages named in args
+	force  bool
+	dryRun bool
+	// keep leaves the old versions installed next to the new ones
+	keep bool
+}
+
+// runUpgrade upgrades names, or every package with opts.all, and prints
+// the result.
+func runUpgrade(ctx context.Context, args []string, opts upgradeOptions) error {
+	open := newManagerWithOptions
+	if opts.dryRun {
+		open = newReadOnlyManager
+	}
+	mgr, cfg, _, formulaRes, err := open(false)
+	if err != nil {
+		return err
+	}
+	_, _, _, caskRes, err := open(true)
+	if err != nil {
+		return err
+	}
+
+	if !opts.dryRun {
+		mgr.Reconcile()
+		mgr.Flush()
+
+		if err := mgr.Begin(commandLine()); err != nil {
+			return err
+		}
+		defer mgr.Commit()
+	}
+
+	installed, err := mgr.ListInstalled()
+	if err != nil {
+		return err
+	}
+
+	if len(installed) == 0 {
+		if jsonOutput {
+			return printJSON(newPackagesResult(nil))
+		}
+		fmt.Printf("%s No packages installed\n", dim("○"))
+		return nil
+	}
+
+	var pinned []string
+
+	names := args
+	if opts.all {
+		for name, pkg := range installed {
+			if pkg.IsDep || slices.Contains(args, name) {
+				continue
 			}
-			_, _, _, caskRes, err := newManagerWithOptions(true)
-			if err != nil {
-				return err
+			// --force only overrides pins of packages named explicitly
+			if pkg.Pinned {
+				pinned = append(pinned, name)
+				continue
 			}
+			names = append(names, name)
+		}
+	}
 
-			mgr.Reconcile()
-			mgr.Flush()
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(min(len(names), cfg.MaxParallel))
 
-			installed, err := mgr.ListInstalled()
-			if err != nil {
-				return err
-			}
+	mu := &sync.Mutex{}
+	var errs []error
+	var upgraded []string
+	var upToDate []string
+	var results []packageResult
+	var dryPlans []*manager.PlannedPackage
 
-			if len(installed) == 0 {
-				fmt.Printf("%s No packages installed\n", dim("○"))
+	for _, name := range names {
+		g.Go(func() error {
+			installedPkg, ok := installed[name]
+			if !ok {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: not installed", name))
+				results = append(results, failedResult(name, fmt.Errorf("not installed")))
+				mu.Unlock()
 				return nil
 			}
 
-			names := args
-			if all {
-				for name, pkg := range installed {
-					if pkg.IsDep {
-						continue
-					}
-					names = append(names, name)
-				}
+			if installedPkg.Pinned && !opts.force {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: pinned (use --force to upgrade)", name))
+				results = append(results, failedResult(name, fmt.Errorf("pinned (use --force to upgrade)")))
+				mu.Unlock()
+				return nil
 			}
 
-			g, ctx := errgroup.WithContext(cmd.Context())
-			g.SetLimit(min(len(names), cfg.MaxParallel))
-
-			mu := &sync.Mutex{}
-			var errs []error
-			var upgraded []string
-			var upToDate []string
-
-			for _, name := range names {
-				g.Go(func() error {
-					installedPkg, ok := installed[name]
-					if !ok {
-						mu.Lock()
-						errs = append(errs, fmt.Errorf("%s: not installed", name))
-						mu.Unlock()
-						return nil
-					}
+			var res *resolver.Resolver
+			if installedPkg.IsCask {
+				res = caskRes
+			} else {
+				res = formulaRes
+			}
 
-					var res *resolver.Resolver
-					if installedPkg.IsCask {
-						res = caskRes
-					} else {
-						res = formulaRes
-					}
+			resolved, err := resolve(ctx, res, name)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %v", name, err))
+				results = append(results, failedResult(name, err))
+				mu.Unlock()
+				return nil
+			}
 
-					stop := withSpinner(ctx, fmt.Sprintf("Resolving %s...", name))
-					resolved, err := res.Resolve(ctx, name)
-					stop()
-					if err != nil {
-						mu.Lock()
-						errs = append(errs, fmt.Errorf("%s: %v", name, err))
-						mu.Unlock()
-						return nil
-					}
+			rootPkg := toPackage(resolved[len(resolved)-1], "")
 
-					var depNames []string
-
-					for _, rp := range resolved {
-						if !rp.IsDep {
-							continue
-						}
-						pkg, err := mgr.Install(ctx, domain.Package{
-							Name:        rp.Formula.Name,
-							Version:     rp.Formula.Version,
-							Revision:    rp.Formula.Revision,
-							FullVersion: rp.Formula.FullVersion(),
-							DownloadURL: rp.Formula.URL,
-							SHA256:      rp.Formula.SHA256,
-							IsDep:       true,
-						})
-						if err != nil {
-							mu.Lock()
-							upgraded = append(upgraded, fmt.Sprintf("  %s %s: %v %s",
-								dim("↳"), rp.Formula.Name, err, dim("(skipped)")))
-							mu.Unlock()
-							continue
-						}
-						depNames = append(depNames, rp.Formula.Name)
-						mu.Lock()
-						upgraded = append(upgraded, fmt.Sprintf("  %s %s%s%s %s",
-							dim("↳"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(dependency)")))
-						mu.Unlock()
+			if opts.dryRun {
+				var pkgs []domain.Package
+				for _, rp := range resolved {
+					if rp.IsDep {
+						pkgs = append(pkgs, toPackage(rp, ""))
 					}
+				}
+				upgrading := installedPkg.FullVersion() != rootPkg.FullVersion
+				if upgrading {
+					pkgs = append(pkgs, rootPkg)
+				}
 
-					rootFormula := &resolved[len(resolved)-1].Formula
+				plans, err := planPackages(ctx, mgr, pkgs, upgrading)
 
-					if installedPkg.FullVersion() == rootFormula.FullVersion() {
-						mu.Lock()
-						upToDate = append(upToDate, name)
-						mu.Unlock()
-						return nil
-					}
-
-					oldVersion := installedPkg.FullVersion()
-
-					pkg, err := mgr.Upgrade(ctx, domain.Package{
-						Name:        name,
-						Version:     installedPkg.Version,
-						FullVersion: installedPkg.FullVersion(),
-						IsCask:      installedPkg.IsCask,
-					}, domain.Package{
-						Name:        rootFormula.Name,
-						Version:     rootFormula.Version,
-						Revision:    rootFormula.Revision,
-						FullVersion: rootFormula.FullVersion(),
-						DownloadURL: rootFormula.URL,
-						SHA256:      rootFormula.SHA256,
-						IsCask:      rootFormula.IsCask,
-					})
-					if err != nil {
-						mu.Lock()
-						errs = append(errs, fmt.Errorf("%s: %v", name, err))
-						mu.Unlock()
-						return nil
-					}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", name, err))
+					results = append(results, failedResult(name, err))
+					return nil
+				}
+				if !upgrading {
+					upToDate = append(upToDate, name)
+				}
+				dryPlans = append(dryPlans, plans...)
+				return nil
+			}
 
-					if len(depNames) > 0 {
-						mgr.SetDependencies(pkg.Name, depNames)
-					}
+			var depNames []string
 
+			for _, rp := range resolved {
+				if !rp.IsDep {
+					continue
+				}
+				pkg, err := mgr.Install(ctx, toPackage(rp, ""))
+				if err != nil {
 					mu.Lock()
-					upgraded = append(upgraded, fmt.Sprintf("%s %s%s%s → %s\n  %s %s\n  %s %s",
-						green("✓"), bold(pkg.Name), bold("-"), bold(oldVersion), bold(pkg.FullVersion()),
-						cyan("cache:"), filepath.Join(cfg.CacheDir, pkg.Name, pkg.FullVersion()),
-						cyan("path:"), filepath.Join(cfg.PackagesDir, pkg.Name, pkg.FullVersion())))
+					upgraded = append(upgraded, fmt.Sprintf("  %s %s: %v %s",
+						dim("↳"), rp.Formula.Name, err, dim("(skipped)")))
+					results = append(results, packageResult{
+						Name:   rp.Formula.Name,
+						Status: statusSkipped,
+						IsDep:  true,
+						Error:  err.Error(),
+					})
 					mu.Unlock()
+					continue
+				}
+				depNames = append(depNames, rp.Formula.Name)
+				mu.Lock()
+				upgraded = append(upgraded, fmt.Sprintf("  %s %s%s%s %s",
+					dim("↳"), bold(pkg.Name), bold("-"), bold(pkg.FullVersion()), dim("(dependency)")))
+				results = append(results, newPackageResult(cfg, pkg, statusInstalled))
+				mu.Unlock()
+			}
 
-					return nil
+			if installedPkg.FullVersion() == rootPkg.FullVersion {
+				mu.Lock()
+				upToDate = append(upToDate, name)
+				results = append(results, packageResult{
+					Name:    name,
+					Version: installedPkg.FullVersion(),
+					Status:  statusUpToDate,
+					IsCask:  installedPkg.IsCask,
 				})
+				mu.Unlock()
+				return nil
 			}
 
-			_ = g.Wait()
+			oldVersion := installedPkg.FullVersion()
 
-			if err := mgr.Flush(); err != nil {
-				return fmt.Errorf("failed to save state: %w", err)
+			upgrade := mgr.Upgrade
+			if opts.keep && !installedPkg.IsCask {
+				upgrade = mgr.UpgradeKeep
 			}
-
-			fmt.Println()
-			for _, s := range upgraded {
-				fmt.Printf("%s\n", s)
-			}
-			for _, name := range upToDate {
-				fmt.Printf("%s %s already up-to-date\n", dim("○"), name)
+			pkg, err := upgrade(ctx, domain.Package{
+				Name:        name,
+				Version:     installedPkg.Version,
+				FullVersion: installedPkg.FullVersion(),
+				IsCask:      installedPkg.IsCask,
+			}, rootPkg)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %v", name, err))
+				results = append(results, failedResult(name, err))
+				mu.Unlock()
+				return nil
 			}
 
-			if len(errs) > 0 {
-				for _, e := range errs {
-					fmt.Printf("%s %s\n", red("✗"), e)
-				}
-				return fmt.Errorf("failed to upgrade %d package(s)", len(errs))
+			if len(depNames) > 0 {
+				mgr.SetDependencies(pkg.Name, depNames)
 			}
 
+			mu.Lock()
+			upgraded = append(upgraded, fmt.Sprintf("%s %s%s%s → %s\n  %s %s\n  %s %s",
+				green("✓"), bold(pkg.Name), bold("-"), bold(oldVersion), bold(pkg.FullVersion()),
+				cyan("cache:"), filepath.Join(cfg.CacheDir, pkg.Name, pkg.FullVersion()),
+				cyan("path:"), filepath.Join(cfg.PackagesDir, pkg.Name, pkg.FullVersion())))
+			upgradedResult := newPackageResult(cfg, pkg, statusUpgraded)
+			upgradedResult.OldVersion = oldVersion
+			results = append(results, upgradedResult)
+			mu.Unlock()
+
 			return nil
-		},
+		})
 	}
 
-	cmd.Flags().BoolVar(&all, "all", false, "Upgrade all installed packages")
-	return cmd
+	_ = g.Wait()
+
+	if opts.dryRun {
+		return printUpgradePlan(dryPlans, upToDate, pinned, results, errs)
+	}
+
+	if err := mgr.Flush(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if jsonOutput {
+		slices.SortStableFunc(results, func(a, b packageResult) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		for _, name := range pinned {
+			results = append(results, packageResult{
+				Name:    name,
+				Version: installed[name].FullVersion(),
+				Status:  statusPinned,
+			})
+		}
+		if err := printJSON(newPackagesResult(results)); err != nil {
+			return err
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("failed to upgrade %d package(s)", len(errs))
+		}
+		return nil
+	}
+
+	fmt.Println()
+	for _, s := range upgraded {
+		fmt.Printf("%s\n", s)
+	}
+	for _, name := range upToDate {
+		fmt.Printf("%s %s already up-to-date\n", dim("○"), name)
+	}
+	for _, name := range pinned {
+		fmt.Printf("%s %s pinned, skipped\n", dim("○"), name)
+	}
+
+	if len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Printf("%s %s\n", red("✗"), e)
+		}
+		return fmt.Errorf("failed to upgrade %d package(s)", len(errs))
+	}
+
+	return nil
+}
+
+func printUpgradePlan(plans []*manager.PlannedPackage, upToDate, pinned []string, results []packageResult, errs []error) error {
+	// Roots sharing a dependency each planned it, keep the first
+	seen := make(map[string]bool)
+	var unique []*manager.PlannedPackage
+	for _, p := range plans {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			unique = append(unique, p)
+		}
+	}
+
+	if jsonOutput {
+		if err := printJSON(dryRunResult{Packages: unique, Failures: results}); err != nil {
+			return err
+		}
+	} else {
+		printPlans(unique)
+		fmt.Println()
+		for _, name := range upToDate {
+			fmt.Printf("%s %s already up-to-date\n", dim("○"), name)
+		}
+		for _, name := range pinned {
+			fmt.Printf("%s %s pinned, skipped\n", dim("○"), name)
+		}
+		for _, e := range errs {
+			fmt.Printf("%s %s\n", red("✗"), e)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to plan %d package(s)", len(errs))
+	}
+	return nil
 }
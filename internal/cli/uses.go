@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+type usesResult struct {
+	Name       string          `json:"name"`
+	Dependents []dependentItem `json:"dependents"`
+}
+
+type dependentItem struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Installed bool   `json:"installed"`
+	IsDep     bool   `json:"is_dep,omitempty"`
+}
+
+func newUsesCmd() *cobra.Command {
+	var recursive bool
+	var fromRegistry bool
+
+	cmd := &cobra.Command{
+		Use:   "uses <name>",
+		Short: "List packages that depend on a package",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, _, reg, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			name := args[0]
+
+			installed, err := mgr.ListInstalled()
+			if err != nil {
+				return err
+			}
+
+			var dependents func(dep string) ([]string, error)
+
+			if fromRegistry {
+				stop := withSpinner(cmd.Context(), "Loading formulae index...")
+				formulae, err := reg.Search(cmd.Context(), "")
+				stop()
+				if err != nil {
+					return err
+				}
+
+				reverse := make(map[string][]string)
+				for _, f := range formulae {
+					for _, dep := range f.Dependencies {
+						reverse[dep] = append(reverse[dep], f.Name)
+					}
+				}
+				dependents = func(dep string) ([]string, error) {
+					return reverse[dep], nil
+				}
+			} else {
+				dependents = mgr.Dependents
+			}
+
+			var users []string
+			seen := map[string]bool{name: true}
+			queue := []string{name}
+
+			for len(queue) > 0 {
+				current := queue[0]
+				queue = queue[1:]
+
+				direct, err := dependents(current)
+				if err != nil {
+					return err
+				}
+				for _, user := range direct {
+					if seen[user] {
+						continue
+					}
+					seen[user] = true
+					users = append(users, user)
+					if recursive {
+						queue = append(queue, user)
+					}
+				}
+			}
+
+			slices.Sort(users)
+
+			if jsonOutput {
+				out := usesResult{Name: name, Dependents: []dependentItem{}}
+				for _, user := range users {
+					item := dependentItem{Name: user}
+					if pkg, ok := installed[user]; ok {
+						item.Version = pkg.FullVersion()
+						item.Installed = true
+						item.IsDep = pkg.IsDep
+					}
+					out.Dependents = append(out.Dependents, item)
+				}
+				return printJSON(out)
+			}
+
+			if len(users) == 0 {
+				scope := "installed package"
+				if fromRegistry {
+					scope = "formula"
+				}
+				fmt.Printf("%s No %s depends on %s\n", dim("○"), scope, name)
+				return nil
+			}
+
+			for _, user := range users {
+				line := fmt.Sprintf("%s %s", green("●"), bold(user))
+				if pkg, ok := installed[user]; ok {
+					if fromRegistry {
+						line += " " + green("✓")
+					} else {
+						line = fmt.Sprintf("%s %s", green("●"), bold(fmt.Sprintf("%s-%s", pkg.Name, pkg.FullVersion())))
+						if pkg.IsDep {
+							line += " " + dim("(dependency)")
+						}
+					}
+				}
+				fmt.Println(line)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Include transitive dependents")
+	cmd.Flags().BoolVar(&fromRegistry, "registry", false, "Search the whole formulae index instead of installed packages")
+	return cmd
+}
@@ -13,6 +13,14 @@ func newVersionCmd() *cobra.Command {
 		Use:   "version",
 		Short: "Print the version of chatr",
 		Run: func(cmd *cobra.Command, args []string) {
+			if jsonOutput {
+				printJSON(struct {
+					Version string `json:"version"`
+					OS      string `json:"os"`
+					Arch    string `json:"arch"`
+				}{version.Version, runtime.GOOS, runtime.GOARCH})
+				return
+			}
 			fmt.Printf("%s%s%s%s%s%s%s\n", bold("chatr"), bold("-"), bold(version.Version),
 				bold("-"), bold(runtime.GOOS), bold("/"), bold(runtime.GOARCH))
 		},
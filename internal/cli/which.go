@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/teamcutter/chatr/internal/config"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+type whichResult struct {
+	Binary string       `json:"binary"`
+	Path   string       `json:"path"`
+	Target string       `json:"target,omitempty"`
+	Active string       `json:"active,omitempty"`
+	Owners []ownerEntry `json:"owners"`
+}
+
+type ownerEntry struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	Active    bool     `json:"active"`
+	Installed bool     `json:"installed"`
+	Projects  []string `json:"projects,omitempty"`
+}
+
+func newWhichCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "which <binary>",
+		Short:             "Show which package provides a linked binary",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeBinaries,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, cfg, _, _, err := newReadOnlyManager(false)
+			if err != nil {
+				return err
+			}
+
+			name := args[0]
+			linkPath := filepath.Join(cfg.BinDir, name)
+			target, _ := os.Readlink(linkPath)
+
+			owners, active, err := mgr.BinaryOwners(name)
+			if err != nil {
+				return err
+			}
+			if len(owners) == 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("no installed package provides %s", name)
+			}
+
+			entries := make([]ownerEntry, len(owners))
+			for i, pkg := range owners {
+				installed, projects, err := ownerUse(mgr, pkg)
+				if err != nil {
+					return err
+				}
+				entries[i] = ownerEntry{
+					Name:      pkg.Name,
+					Version:   pkg.FullVersion(),
+					Active:    pkg == active,
+					Installed: installed,
+					Projects:  projects,
+				}
+			}
+
+			if jsonOutput {
+				res := whichResult{Binary: name, Path: linkPath, Target: target, Owners: entries}
+				if active != nil {
+					res.Active = active.Name
+				}
+				return printJSON(res)
+			}
+
+			if target != "" {
+				fmt.Printf("%s → %s\n", bold(linkPath), target)
+			} else {
+				fmt.Printf("%s %s\n", bold(linkPath), dim("(not linked)"))
+			}
+
+			// Only the active version of an installed package is linked,
+			// so it is the one to reinstall when the link is wrong
+			var linked *domain.InstalledPackage
+			for i, pkg := range owners {
+				label := pkg.Name + "-" + pkg.FullVersion()
+				switch {
+				case pkg == active:
+					if len(owners) > 1 {
+						label += " " + dim("(active)")
+					}
+					fmt.Printf("  %s %s\n", green("●"), label)
+				case pkg.Active:
+					fmt.Printf("  %s %s %s\n", dim("○"), label, dim("(shadowed)"))
+				default:
+					fmt.Printf("  %s %s%s\n", dim("○"), label, ownerLabel(pkg, entries[i].Installed, entries[i].Projects))
+				}
+				if pkg.Active && linked == nil {
+					linked = pkg
+				}
+			}
+
+			if active == nil && linked != nil {
+				fmt.Printf("%s %s does not point into any package providing it, run %s\n",
+					yellow("!"), linkPath, bold("chatr reinstall "+linked.Name))
+			}
+
+			return nil
+		},
+	}
+}
+
+// completeBinaries completes the names of links in the bin directory.
+func completeBinaries(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	entries, err := os.ReadDir(cfg.BinDir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	return filterCompletions(names, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
@@ -43,28 +43,59 @@ func DefaultConfig() *Config {
 	return cfg
 }
 
-func Load() (*Config, error) {
+// path is the config file chosen with SetPath, usually by --config
+var path string
+
+// SetPath makes Load and Save use the config file at p.
+func SetPath(p string) {
 	configMu.Lock()
 	defer configMu.Unlock()
+	path = p
+}
 
-	cfg := DefaultConfig()
+// Path returns the location of the config file: the one passed to
+// SetPath, then $CHATR_CONFIG, then ~/.chatr/config.toml.
+func Path() string {
+	if path != "" {
+		return path
+	}
+	if p := os.Getenv("CHATR_CONFIG"); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".chatr", "config.toml")
+}
 
-	home, err := os.UserHomeDir()
+// Load returns DefaultConfig overridden by the config file, if there is
+// one, and then by CHATR_* environment variables. It never writes the
+// config file.
+func Load() (*Config, error) {
+	cfg, err := LoadFile()
 	if err != nil {
-		return cfg, nil
+		return nil, err
 	}
 
-	base := filepath.Join(home, ".chatr")
-	configPath := filepath.Join(base, "config.toml")
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		if err := save(cfg); err != nil {
-			return nil, err
-		}
-		return cfg, nil
+	// Everything else fails on use, but a limit of 0 would hang
+	if err := cfg.ValidateKey("max_parallel"); err != nil {
+		return nil, err
 	}
 
-	if _, err := toml.DecodeFile(configPath, cfg); err != nil {
+	return cfg, nil
+}
+
+// LoadFile returns DefaultConfig overridden by the config file only.
+// These are the values Save writes back.
+func LoadFile() (*Config, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg := DefaultConfig()
+
+	if _, err := toml.DecodeFile(Path(), cfg); err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
 
@@ -78,10 +109,7 @@ func Save(cfg *Config) error {
 }
 
 func save(cfg *Config) error {
-	home, _ := os.UserHomeDir()
-	base := filepath.Join(home, ".chatr")
-
-	configPath := filepath.Join(base, "config.toml")
+	configPath := Path()
 
 	os.MkdirAll(filepath.Dir(configPath), 0755)
 	f, err := os.Create(configPath)
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// isolate points the home directory at a temporary one, unsets every
+// CHATR_* variable and returns a config file path inside it that Load
+// uses.
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range append(envNames(), "CHATR_CONFIG") {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	path := filepath.Join(home, "custom", "config.toml")
+	SetPath(path)
+	t.Cleanup(func() { SetPath("") })
+	return path
+}
+
+func envNames() []string {
+	var names []string
+	for _, key := range Keys() {
+		names = append(names, EnvName(key))
+	}
+	return names
+}
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"max_parallel": "CHATR_MAX_PARALLEL",
+		"bin_dir":      "CHATR_BIN_DIR",
+		"state_db":     "CHATR_STATE_DB",
+	} {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CHATR_CONFIG", "")
+	os.Unsetenv("CHATR_CONFIG")
+	t.Cleanup(func() { SetPath("") })
+
+	if got, want := Path(), filepath.Join(home, ".chatr", "config.toml"); got != want {
+		t.Errorf("Path() = %q, want the default %q", got, want)
+	}
+
+	t.Setenv("CHATR_CONFIG", "/env/config.toml")
+	if got := Path(); got != "/env/config.toml" {
+		t.Errorf("Path() = %q, want $CHATR_CONFIG", got)
+	}
+
+	SetPath("/flag/config.toml")
+	if got := Path(); got != "/flag/config.toml" {
+		t.Errorf("Path() = %q, want the --config path over $CHATR_CONFIG", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		// want maps keys to their expected value, "default" for the one
+		// DefaultConfig has
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{"max_parallel": "6", "bin_dir": "default"},
+		},
+		{
+			name: "file over defaults",
+			file: "max_parallel = 3\nbin_dir = \"/file/bin\"\n",
+			want: map[string]string{"max_parallel": "3", "bin_dir": "/file/bin", "lib_dir": "default"},
+		},
+		{
+			name: "env over file",
+			file: "max_parallel = 3\nbin_dir = \"/file/bin\"\n",
+			env:  map[string]string{"CHATR_MAX_PARALLEL": "9"},
+			want: map[string]string{"max_parallel": "9", "bin_dir": "/file/bin"},
+		},
+		{
+			name: "env over defaults",
+			env:  map[string]string{"CHATR_BIN_DIR": "/env/bin", "CHATR_MAX_PARALLEL": "2"},
+			want: map[string]string{"max_parallel": "2", "bin_dir": "/env/bin", "lib_dir": "default"},
+		},
+		{
+			name:    "max_parallel from env that is not a number",
+			env:     map[string]string{"CHATR_MAX_PARALLEL": "many"},
+			wantErr: `CHATR_MAX_PARALLEL: max_parallel must be a number, got "many"`,
+		},
+		{
+			name:    "max_parallel from env that is 0",
+			file:    "max_parallel = 3\n",
+			env:     map[string]string{"CHATR_MAX_PARALLEL": "0"},
+			wantErr: "max_parallel must be greater than 0",
+		},
+		{
+			name:    "max_parallel from file that is negative",
+			file:    "max_parallel = -1\n",
+			wantErr: "max_parallel must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := isolate(t)
+			if tt.file != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defaults := DefaultConfig()
+			for key, want := range tt.want {
+				if want == "default" {
+					want, _ = defaults.Get(key)
+				}
+				if got, _ := cfg.Get(key); got != want {
+					t.Errorf("Load() %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileIgnoresEnv(t *testing.T) {
+	path := isolate(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("max_parallel = 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHATR_MAX_PARALLEL", "9")
+	t.Setenv("CHATR_BIN_DIR", "/env/bin")
+
+	cfg, err := LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxParallel != 3 {
+		t.Errorf("LoadFile() max_parallel = %d, want 3 from the file", cfg.MaxParallel)
+	}
+	if cfg.BinDir != DefaultConfig().BinDir {
+		t.Errorf("LoadFile() bin_dir = %q, want the default", cfg.BinDir)
+	}
+}
+
+func TestLoadNeverWrites(t *testing.T) {
+	path := isolate(t)
+	t.Setenv("CHATR_MAX_PARALLEL", "9")
+
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Load() created %s", path)
+	}
+
+	content := "max_parallel = 3\nunknown = true\n"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("Load() rewrote %s:\n%s", path, data)
+	}
+}
+
+func TestFileKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		// file is the config file, none if empty
+		file string
+		want []string
+	}{
+		{
+			name: "no config file",
+		},
+		{
+			name: "set to the default value",
+			file: "max_parallel = 6\n",
+			want: []string{"max_parallel"},
+		},
+		{
+			name: "in the order of Keys",
+			file: "max_parallel = 3\nbin_dir = \"/opt/bin\"\nunknown = 1\n",
+			want: []string{"bin_dir", "max_parallel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := isolate(t)
+			if tt.file != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := FileKeys()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FileKeys() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
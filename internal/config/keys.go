@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Keys returns the TOML keys of every Config field in declaration order.
+func Keys() []string {
+	t := reflect.TypeFor[Config]()
+	keys := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		keys = append(keys, t.Field(i).Tag.Get("toml"))
+	}
+	return keys
+}
+
+// Get returns the value of the field with the given TOML key.
+func (c *Config) Get(key string) (string, error) {
+	v, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+
+	switch v.Kind() {
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	default:
+		return v.String(), nil
+	}
+}
+
+// Set validates value and assigns it to the field with the given TOML key.
+func (c *Config) Set(key, value string) error {
+	if err := c.set(key, value); err != nil {
+		return err
+	}
+	return c.ValidateKey(key)
+}
+
+func (c *Config) set(key, value string) error {
+	v, err := c.field(key)
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		v.SetInt(int64(n))
+	default:
+		v.SetString(value)
+	}
+	return nil
+}
+
+// EnvName returns the environment variable that overrides a TOML key.
+func EnvName(key string) string {
+	return "CHATR_" + strings.ToUpper(key)
+}
+
+// applyEnv overrides fields with the CHATR_* variables that are set.
+func (c *Config) applyEnv() error {
+	for _, key := range Keys() {
+		value, ok := os.LookupEnv(EnvName(key))
+		if !ok {
+			continue
+		}
+		if err := c.set(key, value); err != nil {
+			return fmt.Errorf("%s: %w", EnvName(key), err)
+		}
+	}
+	return nil
+}
+
+// Unset restores the field with the given TOML key to its default.
+func (c *Config) Unset(key string) error {
+	v, err := c.field(key)
+	if err != nil {
+		return err
+	}
+
+	def, _ := DefaultConfig().field(key)
+	v.Set(def)
+	return nil
+}
+
+// ValidateKey checks the value of a single field. Directories and the
+// parent directories of files must be absolute and writable.
+func (c *Config) ValidateKey(key string) error {
+	switch key {
+	case "max_parallel":
+		if c.MaxParallel <= 0 {
+			return fmt.Errorf("max_parallel must be greater than 0, got %d", c.MaxParallel)
+		}
+		return nil
+	case "manifest_file", "state_db":
+		path, _ := c.Get(key)
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("%s must be an absolute path, got %q", key, path)
+		}
+		if err := checkWritable(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	}
+
+	path, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("%s must be an absolute path, got %q", key, path)
+	}
+	// Casks are only installed on macOS
+	if key == "apps_dir" && runtime.GOOS != "darwin" {
+		return nil
+	}
+	if err := checkWritable(path); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// Validate checks every field and returns all problems found.
+func (c *Config) Validate() error {
+	var errs []error
+	for _, key := range Keys() {
+		if err := c.ValidateKey(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FileKeys returns the keys the config file sets, in the order of Keys.
+// A key set to its default value is still set in the file.
+func FileKeys() ([]string, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	md, err := toml.DecodeFile(Path(), &Config{})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, key := range Keys() {
+		if md.IsDefined(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// UnknownKeys returns keys in the config file that do not match any
+// Config field.
+func UnknownKeys() ([]string, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	md, err := toml.DecodeFile(Path(), &Config{})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, key := range md.Undecoded() {
+		keys = append(keys, key.String())
+	}
+	return keys, nil
+}
+
+func (c *Config) field(key string) (reflect.Value, error) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("toml") == key {
+			return v.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+}
+
+// checkWritable reports whether files can be created in dir, or in its
+// closest existing parent when dir does not exist yet.
+func checkWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".chatr-write-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable", dir)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
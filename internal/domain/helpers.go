@@ -1,8 +1,24 @@
 package domain
 
+import "strings"
+
 func FormatVersion(version, revision string) string {
 	if revision != "0" && revision != "" {
 		return version + "_" + revision
 	}
 	return version
 }
+
+// SplitVersion is the inverse of FormatVersion. A full version without a
+// numeric revision suffix is returned whole with an empty revision.
+func SplitVersion(fullVersion string) (version, revision string) {
+	i := strings.LastIndexByte(fullVersion, '_')
+	if i <= 0 {
+		return fullVersion, ""
+	}
+	revision = fullVersion[i+1:]
+	if revision == "" || revision[0] == '0' || strings.Trim(revision, "0123456789") != "" {
+		return fullVersion, ""
+	}
+	return fullVersion[:i], revision
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "testing"
+
+func TestSplitVersion(t *testing.T) {
+	tests := []struct {
+		fullVersion string
+		version     string
+		revision    string
+	}{
+		{"1.7.1", "1.7.1", ""},
+		{"1.7.1_1", "1.7.1", "1"},
+		{"1.7.1_12", "1.7.1", "12"},
+		{"2024-03-01_2", "2024-03-01", "2"},
+		{"1.0_beta", "1.0_beta", ""},
+		{"1.0_beta_3", "1.0_beta", "3"},
+		{"1.0_", "1.0_", ""},
+		{"1.0_0", "1.0_0", ""},
+		{"_1", "_1", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		version, revision := SplitVersion(tt.fullVersion)
+		if version != tt.version || revision != tt.revision {
+			t.Errorf("SplitVersion(%q) = %q, %q, want %q, %q", tt.fullVersion, version, revision, tt.version, tt.revision)
+		}
+		if got := FormatVersion(version, revision); got != tt.fullVersion {
+			t.Errorf("FormatVersion(SplitVersion(%q)) = %q", tt.fullVersion, got)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 type Fetcher interface {
 	Fetch(ctx context.Context, pkg Package) FetchResult
+	Size(ctx context.Context, pkg Package) (int64, error)
 }
 
 type Cache interface {
@@ -19,6 +20,7 @@ type Cache interface {
 type Extractor interface {
 	Extract(src, dest string) error
 	ExtractApps(src, dest string) ([]string, error)
+	List(src string) ([]ArchiveEntry, error)
 }
 
 type State interface {
@@ -26,10 +28,19 @@ type State interface {
 	Save(m *Manifest) error
 	IsInstalled(name string) (bool, *InstalledPackage, error)
 	Add(pkg *InstalledPackage) error
+	AddVersion(pkg *InstalledPackage) error
 	Remove(name string) error
+	RemoveVersion(name, fullVersion string) error
+	Versions(name string) ([]*InstalledPackage, error)
 	Flush() error
 	ListInstalled() (map[string]*InstalledPackage, error)
 	BeginInstall(pkg *InstalledPackage) error
+	SetProjectPackages(project string, pkgs []ProjectPackage) error
+	ProjectPackages() ([]ProjectPackage, error)
+	BeginTransaction(t *Transaction) error
+	UpdateTransaction(t *Transaction) error
+	DeleteTransaction(id int64) error
+	Transactions() ([]*Transaction, error)
 }
 
 type Registry interface {
@@ -37,3 +48,7 @@ type Registry interface {
 	Search(ctx context.Context, query string) ([]Formula, error)
 	GetVersion(ctx context.Context, name string) (string, error)
 }
+
+type EventSink interface {
+	Emit(e Event)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"io/fs"
+	"time"
+)
 
 type Package struct {
 	Name        string
@@ -21,24 +24,46 @@ type FetchResult struct {
 }
 
 type InstalledPackage struct {
-	Name         string    `json:"name"`
-	Version      string    `json:"version"`
-	Revision     string    `json:"revision,omitempty"`
-	URL          string    `json:"url"`
-	Path         string    `json:"path"`
-	Binaries     []string  `json:"binaries"`
-	Libs         []string  `json:"libs,omitempty"`
-	Apps         []string  `json:"apps,omitempty"`
-	Dependencies []string  `json:"dependencies,omitempty"`
-	IsDep        bool      `json:"is_dep,omitempty"`
-	IsCask       bool      `json:"is_cask,omitempty"`
-	InstalledAt  time.Time `json:"installed_at"`
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Revision     string   `json:"revision,omitempty"`
+	URL          string   `json:"url"`
+	Path         string   `json:"path"`
+	Binaries     []string `json:"binaries"`
+	Libs         []string `json:"libs,omitempty"`
+	Apps         []string `json:"apps,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+	IsDep        bool     `json:"is_dep,omitempty"`
+	IsCask       bool     `json:"is_cask,omitempty"`
+	Pinned       bool     `json:"pinned,omitempty"`
+	// Active marks the version linked into the bin and lib directories
+	// among the installed versions of a package
+	Active      bool      `json:"-"`
+	InstalledAt time.Time `json:"installed_at"`
 }
 
 func (p InstalledPackage) FullVersion() string {
 	return FormatVersion(p.Version, p.Revision)
 }
 
+// ProjectPackage is a package version provisioned for the project
+// whose project file is in Project. Its directory is kept while a
+// project uses it, even when it is not installed.
+type ProjectPackage struct {
+	Project     string `json:"project"`
+	Name        string `json:"name"`
+	FullVersion string `json:"version"`
+}
+
+// ArchiveEntry is a file in an archive, listed without extracting it.
+// Name is slash separated and relative to the root of the archive,
+// Linkname is the target of a symlink.
+type ArchiveEntry struct {
+	Name     string
+	Mode     fs.FileMode
+	Linkname string
+}
+
 type Manifest struct {
 	Packages map[string]*InstalledPackage `json:"packages"`
 }
@@ -63,3 +88,82 @@ type Formula struct {
 func (f Formula) FullVersion() string {
 	return FormatVersion(f.Version, f.Revision)
 }
+
+// Progress phases reported through an EventSink.
+const (
+	PhaseResolve  = "resolve"
+	PhaseDownload = "download"
+	PhaseExtract  = "extract"
+	PhaseLink     = "link"
+	PhaseFinish   = "finish"
+)
+
+// Event reports progress of a single package. Download events are sent
+// repeatedly while bytes arrive; every phase ends with a Done event that
+// carries its duration.
+type Event struct {
+	Time     time.Time
+	Package  string
+	Phase    string
+	Bytes    int64
+	Total    int64
+	Duration time.Duration
+	Done     bool
+	Err      error
+}
+
+// Transaction outcomes. A transaction is pending while its command runs
+// and recovered when a later run found it interrupted.
+const (
+	OutcomePending   = "pending"
+	OutcomeSuccess   = "success"
+	OutcomeFailed    = "failed"
+	OutcomeRecovered = "recovered"
+)
+
+// Package changes recorded in a transaction
+const (
+	ActionInstall   = "install"
+	ActionUpgrade   = "upgrade"
+	ActionReinstall = "reinstall"
+	ActionRemove    = "remove"
+	ActionSwitch    = "switch"
+)
+
+// Transaction records one command that changed installed packages.
+type Transaction struct {
+	ID      int64           `json:"id"`
+	Time    time.Time       `json:"time"`
+	Command string          `json:"command"`
+	Changes []PackageChange `json:"changes"`
+	Outcome string          `json:"outcome"`
+}
+
+// PackageChange is one package affected by a transaction. OldVersion is
+// empty for installs and NewVersion for removals. OldURL and the
+// remaining fields describe the package before the change, so that it
+// can be rolled back. Inactive marks installs and removals of a version
+// kept next to the active one, which leave the active version alone.
+type PackageChange struct {
+	Name         string   `json:"name"`
+	Action       string   `json:"action"`
+	OldVersion   string   `json:"old_version,omitempty"`
+	NewVersion   string   `json:"new_version,omitempty"`
+	OldURL       string   `json:"old_url,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+	IsDep        bool     `json:"is_dep,omitempty"`
+	IsCask       bool     `json:"is_cask,omitempty"`
+	Inactive     bool     `json:"inactive,omitempty"`
+	Outcome      string   `json:"outcome"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Touches reports whether the transaction changed the named package.
+func (t Transaction) Touches(name string) bool {
+	for _, c := range t.Changes {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
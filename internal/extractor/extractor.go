@@ -3,6 +3,8 @@ package extractor
 import (
 	"fmt"
 	"strings"
+
+	"github.com/teamcutter/chatr/internal/domain"
 )
 
 type Extractor struct {
@@ -54,6 +56,20 @@ func (e *Extractor) ExtractApps(src, dst string) ([]string, error) {
 	}
 }
 
+// List returns the entries of a tar or zip archive without extracting it.
+func (e *Extractor) List(src string) ([]domain.ArchiveEntry, error) {
+	lower := strings.ToLower(src)
+
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return e.zip.List(src)
+	case isTarArchive(lower):
+		return e.tar.List(src)
+	default:
+		return nil, fmt.Errorf("cannot list archive: %s", src)
+	}
+}
+
 func isTarArchive(name string) bool {
 	tarExts := []string{".tar.gz", ".tar.zst", ".tar.xz", ".tar.bz2", ".tgz", ".txz", ".tzst", ".tbz2", ".tar"}
 	for _, ext := range tarExts {
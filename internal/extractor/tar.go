@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/klauspost/compress/zstd"
+	"github.com/teamcutter/chatr/internal/domain"
 	"github.com/ulikunitz/xz"
 )
 
@@ -83,6 +84,41 @@ func (te *TARExtractor) Extract(src, dst string) error {
 	return nil
 }
 
+// List reads the headers of the archive. The contents are still
+// decompressed to get from one header to the next, but nothing is written.
+func (te *TARExtractor) List(src string) ([]domain.ArchiveEntry, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, cleanup, err := te.getDecompressor(file)
+	if err != nil {
+		return nil, err
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+
+	var entries []domain.ArchiveEntry
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, domain.ArchiveEntry{
+			Name:     header.Name,
+			Mode:     header.FileInfo().Mode(),
+			Linkname: header.Linkname,
+		})
+	}
+}
+
 // https://gist.github.com/leommoore/f9e57ba2aa4bf197ebc5 - this is AWESOME
 func (te *TARExtractor) getDecompressor(file *os.File) (io.Reader, func(), error) {
 	header := make([]byte, 6)
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/teamcutter/chatr/internal/domain"
 )
 
 type ZIPExtractor struct{}
@@ -80,6 +82,35 @@ func (ze *ZIPExtractor) Extract(src, dst string) error {
 	return nil
 }
 
+// List reads the central directory of the ZIP, and the targets of
+// symlinks, which are stored as their contents.
+func (ze *ZIPExtractor) List(src string) ([]domain.ArchiveEntry, error) {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("zip: %w", err)
+	}
+	defer r.Close()
+
+	entries := make([]domain.ArchiveEntry, 0, len(r.File))
+	for _, f := range r.File {
+		entry := domain.ArchiveEntry{Name: f.Name, Mode: f.Mode()}
+		if f.Mode()&os.ModeSymlink != 0 {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			target, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			entry.Linkname = string(target)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 // ExtractApps extracts only .app bundles from the ZIP directly to dst.
 func (ze *ZIPExtractor) ExtractApps(src, dst string) ([]string, error) {
 	r, err := zip.OpenReader(src)
@@ -14,17 +14,20 @@ import (
 	"strings"
 	"time"
 
-	"github.com/schollz/progressbar/v3"
 	"github.com/teamcutter/chatr/internal/domain"
 )
 
+// progressInterval throttles download events sent to the sink
+const progressInterval = 200 * time.Millisecond
+
 type HTTPFetcher struct {
 	client    *http.Client
 	outputDir string
 	timeout   time.Duration
+	events    domain.EventSink
 }
 
-func New(outputDir string, timeout time.Duration) *HTTPFetcher {
+func New(outputDir string, timeout time.Duration, events domain.EventSink) *HTTPFetcher {
 	return &HTTPFetcher{
 		client: &http.Client{
 			Timeout: timeout,
@@ -37,6 +40,7 @@ func New(outputDir string, timeout time.Duration) *HTTPFetcher {
 		},
 		outputDir: outputDir,
 		timeout:   timeout,
+		events:    events,
 	}
 }
 
@@ -45,29 +49,10 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, pkg domain.Package) domain.Fetc
 	filename := fmt.Sprintf("%s-%s%s", pkg.Name, pkg.FullVersion, ext)
 	dst := filepath.Join(f.outputDir, filename)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", pkg.DownloadURL, nil)
-	if err != nil {
-		return domain.FetchResult{Package: pkg.Name, Version: pkg.Version, Error: err}
-	}
-
-	resp, err := f.client.Do(req)
+	resp, err := f.do(ctx, http.MethodGet, pkg.DownloadURL)
 	if err != nil {
 		return domain.FetchResult{Package: pkg.Name, Version: pkg.Version, Error: err}
 	}
-
-	if resp.StatusCode == http.StatusUnauthorized && strings.Contains(pkg.DownloadURL, "ghcr.io") {
-		resp.Body.Close()
-		token, err := f.getGHCRToken(ctx, resp.Header.Get("WWW-Authenticate"))
-		if err != nil {
-			return domain.FetchResult{Package: pkg.Name, Version: pkg.Version, Error: err}
-		}
-		req, _ = http.NewRequestWithContext(ctx, "GET", pkg.DownloadURL, nil)
-		req.Header.Set("Authorization", "Bearer "+token)
-		resp, err = f.client.Do(req)
-		if err != nil {
-			return domain.FetchResult{Package: pkg.Name, Version: pkg.Version, Error: err}
-		}
-	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -88,12 +73,14 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, pkg domain.Package) domain.Fetc
 	}
 	defer file.Close()
 
-	bar := progressbar.DefaultBytes(
-		resp.ContentLength,
-		fmt.Sprintf("Downloading %s", pkg.Name),
-	)
+	progress := &progressWriter{
+		events:  f.events,
+		pkg:     pkg.Name,
+		total:   resp.ContentLength,
+		started: time.Now(),
+	}
 
-	writers := []io.Writer{file, bar}
+	writers := []io.Writer{file, progress}
 
 	h := sha256.New()
 	if pkg.SHA256 != "" {
@@ -101,8 +88,10 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, pkg domain.Package) domain.Fetc
 	}
 
 	if _, err := io.Copy(io.MultiWriter(writers...), resp.Body); err != nil {
+		progress.finish(err)
 		return domain.FetchResult{Package: pkg.Name, Version: pkg.Version, Error: err}
 	}
+	progress.finish(nil)
 
 	if pkg.SHA256 != "" {
 		actual := hex.EncodeToString(h.Sum(nil))
@@ -119,6 +108,87 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, pkg domain.Package) domain.Fetc
 	return domain.FetchResult{Package: pkg.Name, Version: pkg.Version, Path: dst}
 }
 
+// progressWriter counts downloaded bytes and reports them to the sink
+// at most once per progressInterval.
+type progressWriter struct {
+	events  domain.EventSink
+	pkg     string
+	total   int64
+	written int64
+	started time.Time
+	last    time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if now := time.Now(); p.events != nil && now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.events.Emit(domain.Event{
+			Time:    now,
+			Package: p.pkg,
+			Phase:   domain.PhaseDownload,
+			Bytes:   p.written,
+			Total:   p.total,
+		})
+	}
+	return len(b), nil
+}
+
+func (p *progressWriter) finish(err error) {
+	if p.events == nil {
+		return
+	}
+	p.events.Emit(domain.Event{
+		Time:     time.Now(),
+		Package:  p.pkg,
+		Phase:    domain.PhaseDownload,
+		Bytes:    p.written,
+		Total:    p.total,
+		Duration: time.Since(p.started),
+		Done:     true,
+		Err:      err,
+	})
+}
+
+// Size returns the download size of pkg, or -1 if the server does not report it.
+func (f *HTTPFetcher) Size(ctx context.Context, pkg domain.Package) (int64, error) {
+	resp, err := f.do(ctx, http.MethodHead, pkg.DownloadURL)
+	if err != nil {
+		return -1, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return resp.ContentLength, nil
+}
+
+func (f *HTTPFetcher) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && strings.Contains(url, "ghcr.io") {
+		resp.Body.Close()
+		token, err := f.getGHCRToken(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+		req, _ = http.NewRequestWithContext(ctx, method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return f.client.Do(req)
+	}
+
+	return resp, nil
+}
+
 // Detailed here
 // https://stackoverflow.com/questions/79168476/how-to-get-api-token-to-github-container-registry
 func (f *HTTPFetcher) getGHCRToken(ctx context.Context, wwwAuth string) (string, error) {
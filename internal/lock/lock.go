@@ -0,0 +1,206 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/tomlfile"
+)
+
+// FileName is the lockfile looked up in the current directory.
+const FileName = "chatr.lock"
+
+// formatVersion is bumped when the lockfile layout changes.
+const formatVersion = 1
+
+// Lock records the exact artifacts of every package in resolved install
+// closures, so they can be installed again after the index moved on.
+type Lock struct {
+	Version  int       `toml:"version"`
+	Packages []Package `toml:"packages"`
+
+	// path is the file the lock was loaded from
+	path string
+}
+
+// Package is one locked artifact. Version is the full version including
+// the revision.
+type Package struct {
+	Name         string   `toml:"name"`
+	Version      string   `toml:"version"`
+	URL          string   `toml:"url"`
+	SHA256       string   `toml:"sha256,omitempty"`
+	Dependencies []string `toml:"dependencies,omitempty"`
+	Cask         bool     `toml:"cask,omitempty"`
+}
+
+// New returns an empty lock.
+func New() *Lock {
+	return &Lock{Version: formatVersion}
+}
+
+// Load reads and validates the lockfile at path.
+func Load(path string) (*Lock, error) {
+	var l Lock
+	if err := tomlfile.Decode(path, &l); err != nil {
+		return nil, err
+	}
+
+	if err := l.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l.path = path
+	return &l, nil
+}
+
+func (l *Lock) validate() error {
+	if l.Version != formatVersion {
+		return fmt.Errorf("unsupported lockfile version %d", l.Version)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range l.Packages {
+		if p.Name == "" {
+			return fmt.Errorf("package without a name")
+		}
+		if p.Version == "" || p.URL == "" {
+			return fmt.Errorf("%s: version and url are required", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("%s is locked more than once", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	for _, p := range l.Packages {
+		for _, dep := range p.Dependencies {
+			if !seen[dep] {
+				return fmt.Errorf("%s depends on %s, which is not locked", p.Name, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns the locked package with the given name.
+func (l *Lock) Get(name string) (*Package, bool) {
+	for i := range l.Packages {
+		if l.Packages[i].Name == name {
+			return &l.Packages[i], true
+		}
+	}
+	return nil, false
+}
+
+// Put locks formula, replacing the entry of the same name.
+func (l *Lock) Put(formula domain.Formula) {
+	p := Package{
+		Name:         formula.Name,
+		Version:      formula.FullVersion(),
+		URL:          formula.URL,
+		SHA256:       formula.SHA256,
+		Dependencies: formula.Dependencies,
+		Cask:         formula.IsCask,
+	}
+
+	if existing, ok := l.Get(formula.Name); ok {
+		*existing = p
+		return
+	}
+	l.Packages = append(l.Packages, p)
+}
+
+// Roots returns the names of locked packages no other locked package
+// depends on, which are the ones that were installed on request.
+func (l *Lock) Roots() []string {
+	depended := make(map[string]bool)
+	for _, p := range l.Packages {
+		for _, dep := range p.Dependencies {
+			depended[dep] = true
+		}
+	}
+
+	var roots []string
+	for _, p := range l.Packages {
+		if !depended[p.Name] {
+			roots = append(roots, p.Name)
+		}
+	}
+	return roots
+}
+
+// Save writes the lock to path sorted by name, replacing any existing
+// file.
+func (l *Lock) Save(path string) error {
+	slices.SortFunc(l.Packages, func(a, b Package) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return toml.NewEncoder(f).Encode(l)
+}
+
+// Registry returns a registry that only knows the locked packages, so
+// resolving against it yields exactly the locked closure.
+func (l *Lock) Registry() domain.Registry {
+	return &registry{lock: l}
+}
+
+type registry struct {
+	lock *Lock
+}
+
+// file names the lockfile in errors.
+func (r *registry) file() string {
+	if r.lock.path == "" {
+		return FileName
+	}
+	return r.lock.path
+}
+
+func (r *registry) Get(ctx context.Context, name string) (*domain.Formula, error) {
+	p, ok := r.lock.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in %s", name, r.file())
+	}
+
+	version, revision := domain.SplitVersion(p.Version)
+	return &domain.Formula{
+		Name:         p.Name,
+		Version:      version,
+		Revision:     revision,
+		URL:          p.URL,
+		SHA256:       p.SHA256,
+		Dependencies: p.Dependencies,
+		IsCask:       p.Cask,
+	}, nil
+}
+
+func (r *registry) Search(ctx context.Context, query string) ([]domain.Formula, error) {
+	var results []domain.Formula
+	for _, p := range r.lock.Packages {
+		if strings.Contains(p.Name, query) {
+			f, _ := r.Get(ctx, p.Name)
+			results = append(results, *f)
+		}
+	}
+	return results, nil
+}
+
+func (r *registry) GetVersion(ctx context.Context, name string) (string, error) {
+	p, ok := r.lock.Get(name)
+	if !ok {
+		return "", fmt.Errorf("%s is not in %s", name, r.file())
+	}
+	return p.Version, nil
+}
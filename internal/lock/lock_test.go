@@ -0,0 +1,216 @@
+package lock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Package
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			content: "version = 1\n",
+		},
+		{
+			name: "packages",
+			content: `version = 1
+
+[[packages]]
+  name = "jq"
+  version = "1.7.1_1"
+  url = "https://example.com/jq.tar.gz"
+  sha256 = "abc"
+  dependencies = ["oniguruma"]
+
+[[packages]]
+  name = "oniguruma"
+  version = "6.9.9"
+  url = "https://example.com/oniguruma.tar.gz"
+`,
+			want: []Package{
+				{Name: "jq", Version: "1.7.1_1", URL: "https://example.com/jq.tar.gz", SHA256: "abc", Dependencies: []string{"oniguruma"}},
+				{Name: "oniguruma", Version: "6.9.9", URL: "https://example.com/oniguruma.tar.gz"},
+			},
+		},
+		{
+			name:    "unsupported version",
+			content: "version = 2\n",
+			wantErr: "unsupported lockfile version 2",
+		},
+		{
+			name:    "missing version",
+			content: "",
+			wantErr: "unsupported lockfile version 0",
+		},
+		{
+			name:    "package without a name",
+			content: "version = 1\n\n[[packages]]\n  version = \"1.7\"\n  url = \"u\"\n",
+			wantErr: "package without a name",
+		},
+		{
+			name:    "package without a url",
+			content: "version = 1\n\n[[packages]]\n  name = \"jq\"\n  version = \"1.7\"\n",
+			wantErr: "jq: version and url are required",
+		},
+		{
+			name:    "package locked twice",
+			content: "version = 1\n\n[[packages]]\n  name = \"jq\"\n  version = \"1.7\"\n  url = \"u\"\n\n[[packages]]\n  name = \"jq\"\n  version = \"1.6\"\n  url = \"u\"\n",
+			wantErr: "jq is locked more than once",
+		},
+		{
+			name:    "dependency not locked",
+			content: "version = 1\n\n[[packages]]\n  name = \"jq\"\n  version = \"1.7\"\n  url = \"u\"\n  dependencies = [\"oniguruma\"]\n",
+			wantErr: "jq depends on oniguruma, which is not locked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(l.Packages, tt.want) {
+				t.Errorf("Load() packages = %+v, want %+v", l.Packages, tt.want)
+			}
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	l := New()
+	l.Put(domain.Formula{Name: "oniguruma", Version: "6.9.9", URL: "https://example.com/oniguruma.tar.gz"})
+	l.Put(domain.Formula{Name: "jq", Version: "1.7.1", Revision: "1", URL: "https://example.com/jq.tar.gz", SHA256: "abc", Dependencies: []string{"oniguruma"}})
+	l.Put(domain.Formula{Name: "firefox", Version: "128.0", Revision: "0", URL: "https://example.com/firefox.dmg", IsCask: true})
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := l.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Packages are written sorted by name, with the revision as part of
+	// the version unless there is none
+	var names, versions []string
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " = ")
+		switch {
+		case ok && key == "name":
+			names = append(names, value)
+		case ok && key == "version" && strings.HasPrefix(line, " "):
+			versions = append(versions, value)
+		}
+	}
+	if want := []string{`"firefox"`, `"jq"`, `"oniguruma"`}; !slices.Equal(names, want) {
+		t.Errorf("saved names = %v, want %v\n%s", names, want, data)
+	}
+	if want := []string{`"128.0"`, `"1.7.1_1"`, `"6.9.9"`}; !slices.Equal(versions, want) {
+		t.Errorf("saved versions = %v, want %v\n%s", versions, want, data)
+	}
+
+	if _, err := Load(path); err != nil {
+		t.Errorf("Load(Save(l)) error = %v", err)
+	}
+}
+
+func TestPut(t *testing.T) {
+	l := New()
+	l.Put(domain.Formula{Name: "jq", Version: "1.6", URL: "old"})
+	l.Put(domain.Formula{Name: "jq", Version: "1.7", URL: "new"})
+
+	if len(l.Packages) != 1 {
+		t.Fatalf("Put twice locked %d packages, want 1", len(l.Packages))
+	}
+	if p, _ := l.Get("jq"); p.Version != "1.7" || p.URL != "new" {
+		t.Errorf("Put did not replace the entry: %+v", p)
+	}
+}
+
+func TestRoots(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []Package
+		want     []string
+	}{
+		{name: "empty"},
+		{
+			name:     "single package",
+			packages: []Package{{Name: "jq"}},
+			want:     []string{"jq"},
+		},
+		{
+			name: "dependencies are not roots",
+			packages: []Package{
+				{Name: "git", Dependencies: []string{"openssl"}},
+				{Name: "curl", Dependencies: []string{"openssl"}},
+				{Name: "openssl"},
+			},
+			want: []string{"git", "curl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Lock{Version: formatVersion, Packages: tt.packages}
+			if got := l.Roots(); !slices.Equal(got, tt.want) {
+				t.Errorf("Roots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	l := New()
+	l.Put(domain.Formula{Name: "jq", Version: "1.7.1", Revision: "1", URL: "u", SHA256: "abc"})
+	reg := l.Registry()
+
+	f, err := reg.Get(context.Background(), "jq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != "1.7.1" || f.Revision != "1" || f.URL != "u" || f.SHA256 != "abc" {
+		t.Errorf("Get(jq) = %+v, want the locked artifact", f)
+	}
+
+	if _, err := reg.Get(context.Background(), "gh"); err == nil || !strings.Contains(err.Error(), FileName) {
+		t.Errorf("Get(gh) error = %v, want it to name %s", err, FileName)
+	}
+
+	path := filepath.Join(t.TempDir(), "tools.lock")
+	if err := l.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Registry().GetVersion(context.Background(), "gh"); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("GetVersion(gh) error = %v, want it to name %s", err, path)
+	}
+}
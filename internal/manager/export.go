@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// exportFormat is bumped when the export layout changes.
+const exportFormat = 1
+
+// Export is a portable list of the packages installed on request. Unlike
+// the manifest it has no paths or dependency details, so it can be
+// imported on another machine.
+type Export struct {
+	Format     int               `json:"format"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Packages   []ExportedPackage `json:"packages"`
+}
+
+// ExportedPackage is one root package. Version is the full version
+// including the revision.
+type ExportedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	IsCask  bool   `json:"cask,omitempty"`
+	Pinned  bool   `json:"pinned,omitempty"`
+}
+
+// Export lists the installed packages that are not dependencies, sorted
+// by name.
+func (m *Manager) Export() (*Export, error) {
+	installed, err := m.state.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Export{Format: exportFormat, ExportedAt: time.Now().UTC(), Packages: []ExportedPackage{}}
+	for _, pkg := range installed {
+		if pkg.IsDep {
+			continue
+		}
+		e.Packages = append(e.Packages, ExportedPackage{
+			Name:    pkg.Name,
+			Version: pkg.FullVersion(),
+			IsCask:  pkg.IsCask,
+			Pinned:  pkg.Pinned,
+		})
+	}
+	slices.SortFunc(e.Packages, func(a, b ExportedPackage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return e, nil
+}
+
+// ReadExport decodes and validates an export written by Export.
+func ReadExport(r io.Reader) (*Export, error) {
+	var e Export
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&e); err != nil {
+		return nil, fmt.Errorf("invalid export: %w", err)
+	}
+
+	if e.Format != exportFormat {
+		return nil, fmt.Errorf("unsupported export format %d", e.Format)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range e.Packages {
+		if p.Name == "" || p.Version == "" {
+			return nil, fmt.Errorf("invalid export: packages need a name and version")
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("invalid export: %s is listed more than once", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	return &e, nil
+}
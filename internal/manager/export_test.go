@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func TestExport(t *testing.T) {
+	m := newTestManager(t,
+		&domain.InstalledPackage{Name: "jq", Version: "1.7.1", Revision: "1", Dependencies: []string{"oniguruma"}},
+		&domain.InstalledPackage{Name: "oniguruma", IsDep: true},
+		&domain.InstalledPackage{Name: "terraform", Version: "1.5.7", Pinned: true},
+		&domain.InstalledPackage{Name: "firefox", Version: "128.0", IsCask: true},
+	)
+
+	e, err := m.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Format != exportFormat {
+		t.Errorf("Export() format = %d, want %d", e.Format, exportFormat)
+	}
+
+	want := []ExportedPackage{
+		{Name: "firefox", Version: "128.0", IsCask: true},
+		{Name: "jq", Version: "1.7.1_1"},
+		{Name: "terraform", Version: "1.5.7", Pinned: true},
+	}
+	if !reflect.DeepEqual(e.Packages, want) {
+		t.Errorf("Export() packages = %+v, want %+v", e.Packages, want)
+	}
+}
+
+func TestReadExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []ExportedPackage
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			content: `{"format": 1, "exported_at": "2024-03-01T00:00:00Z", "packages": []}`,
+			want:    []ExportedPackage{},
+		},
+		{
+			name: "packages",
+			content: `{"format": 1, "exported_at": "2024-03-01T00:00:00Z", "packages": [
+				{"name": "jq", "version": "1.7.1_1"},
+				{"name": "terraform", "version": "1.5.7", "pinned": true},
+				{"name": "firefox", "version": "128.0", "cask": true}
+			]}`,
+			want: []ExportedPackage{
+				{Name: "jq", Version: "1.7.1_1"},
+				{Name: "terraform", Version: "1.5.7", Pinned: true},
+				{Name: "firefox", Version: "128.0", IsCask: true},
+			},
+		},
+		{
+			name:    "unknown field",
+			content: `{"format": 1, "packages": [{"name": "jq", "version": "1.7", "path": "/opt/jq"}]}`,
+			wantErr: `unknown field "path"`,
+		},
+		{
+			name:    "wrong format",
+			content: `{"format": 2, "packages": []}`,
+			wantErr: "unsupported export format 2",
+		},
+		{
+			name:    "missing format",
+			content: `{"packages": []}`,
+			wantErr: "unsupported export format 0",
+		},
+		{
+			name:    "duplicate package",
+			content: `{"format": 1, "packages": [{"name": "jq", "version": "1.7"}, {"name": "jq", "version": "1.6"}]}`,
+			wantErr: "jq is listed more than once",
+		},
+		{
+			name:    "missing version",
+			content: `{"format": 1, "packages": [{"name": "jq"}]}`,
+			wantErr: "packages need a name and version",
+		},
+		{
+			name:    "not JSON",
+			content: `format = 1`,
+			wantErr: "invalid export",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := ReadExport(strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadExport() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(e.Packages, tt.want) {
+				t.Errorf("ReadExport() packages = %+v, want %+v", e.Packages, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// Begin starts recording a transaction for command. Installs, upgrades,
+// reinstalls and removals made until Commit are recorded in it.
+func (m *Manager) Begin(command string) error {
+	t := &domain.Transaction{
+		Time:    time.Now(),
+		Command: command,
+		Outcome: domain.OutcomePending,
+	}
+	if err := m.state.BeginTransaction(t); err != nil {
+		return err
+	}
+
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	m.tx = t
+	return nil
+}
+
+// Commit finishes the current transaction. It is marked failed if any
+// change failed, and dropped if nothing changed.
+func (m *Manager) Commit() error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	t := m.tx
+	if t == nil {
+		return nil
+	}
+	m.tx = nil
+
+	if len(t.Changes) == 0 {
+		return m.state.DeleteTransaction(t.ID)
+	}
+
+	t.Outcome = domain.OutcomeSuccess
+	for _, c := range t.Changes {
+		if c.Outcome != domain.OutcomeSuccess {
+			t.Outcome = domain.OutcomeFailed
+			break
+		}
+	}
+	return m.state.UpdateTransaction(t)
+}
+
+// Transactions returns the recorded transactions, newest first.
+func (m *Manager) Transactions() ([]*domain.Transaction, error) {
+	return m.state.Transactions()
+}
+
+// track records change as in progress in the current transaction, so an
+// interrupted command still shows it, and returns the function that
+// records its outcome. Without a transaction nothing is recorded.
+func (m *Manager) track(change domain.PackageChange) (done func(err error)) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	t := m.tx
+	if t == nil {
+		return func(error) {}
+	}
+
+	change.Outcome = domain.OutcomePending
+	t.Changes = append(t.Changes, change)
+	i := len(t.Changes) - 1
+	m.state.UpdateTransaction(t)
+
+	return func(err error) {
+		m.txMu.Lock()
+		defer m.txMu.Unlock()
+
+		if err != nil {
+			t.Changes[i].Outcome = domain.OutcomeFailed
+			t.Changes[i].Error = err.Error()
+		} else {
+			t.Changes[i].Outcome = domain.OutcomeSuccess
+		}
+		m.state.UpdateTransaction(t)
+	}
+}
@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// BinaryOwners returns the package versions that provide a binary
+// called name, sorted by package name. Versions installed next to the
+// active one and versions provisioned for projects are included. active
+// is the one the symlink in the bin directory currently points into, nil
+// if none of them.
+func (m *Manager) BinaryOwners(name string) (owners []*domain.InstalledPackage, active *domain.InstalledPackage, err error) {
+	candidates, err := m.ownerCandidates()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, pkg := range candidates {
+		if slices.Contains(pkg.Binaries, name) {
+			owners = append(owners, pkg)
+		}
+	}
+
+	if target, err := os.Readlink(filepath.Join(m.binDir, name)); err == nil {
+		for _, pkg := range owners {
+			if within(target, pkg.Path) {
+				active = pkg
+				break
+			}
+		}
+	}
+
+	return owners, active, nil
+}
+
+// Owner returns the package version a file belongs to. Paths inside
+// the directory of any installed or project version, symlinks in the
+// bin and lib directories and cask apps are recognized. It returns nil
+// if no package owns path.
+func (m *Manager) Owner(path string) (*domain.InstalledPackage, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	installed, err := m.ownerCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	// Links in the bin and lib directories, and project wrappers, are
+	// resolved first, so that the package they point into wins over a
+	// stale name in state
+	candidates := []string{path}
+	if target, ok := FarmTarget(path); ok {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		candidates = append([]string{target}, candidates...)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		candidates = append(candidates, resolved)
+	}
+
+	for _, p := range candidates {
+		for _, pkg := range installed {
+			if pkg.Path != "" && within(p, pkg.Path) {
+				return pkg, nil
+			}
+		}
+	}
+
+	// Only active versions are linked
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	for _, pkg := range installed {
+		if !pkg.Active {
+			continue
+		}
+		switch {
+		case dir == m.binDir && slices.Contains(pkg.Binaries, base),
+			dir == m.libDir && slices.Contains(pkg.Libs, base):
+			return pkg, nil
+		}
+		for _, app := range pkg.Apps {
+			if within(path, filepath.Join(m.appsDir, app)) {
+				return pkg, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// ProjectsUsing returns the roots of the projects whose toolchain uses
+// a package version.
+func (m *Manager) ProjectsUsing(name, version string) ([]string, error) {
+	pkgs, err := m.state.ProjectPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	for _, p := range pkgs {
+		if p.Name == name && p.FullVersion == version {
+			roots = append(roots, p.Project)
+		}
+	}
+	return roots, nil
+}
+
+// ownerCandidates returns every installed version of every package,
+// and the versions provisioned for projects that are not installed,
+// sorted by name with the active version first. Project versions only
+// have their path, binaries and libraries set.
+func (m *Manager) ownerCandidates() ([]*domain.InstalledPackage, error) {
+	installed, err := m.state.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []*domain.InstalledPackage
+	seen := make(map[string]bool)
+	for name := range installed {
+		versions, err := m.state.Versions(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			seen[v.Name+"/"+v.FullVersion()] = true
+			pkgs = append(pkgs, v)
+		}
+	}
+
+	projectPkgs, err := m.state.ProjectPackages()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projectPkgs {
+		if seen[p.Name+"/"+p.FullVersion] {
+			continue
+		}
+		seen[p.Name+"/"+p.FullVersion] = true
+
+		pkg := &domain.InstalledPackage{
+			Name:    p.Name,
+			Version: p.FullVersion,
+			Path:    filepath.Join(m.packagesDir, p.Name, p.FullVersion),
+		}
+		for _, binPath := range findBinaries(pkg.Path) {
+			pkg.Binaries = append(pkg.Binaries, filepath.Base(binPath))
+		}
+		for _, libPath := range findLibraries(pkg.Path) {
+			pkg.Libs = append(pkg.Libs, filepath.Base(libPath))
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	slices.SortFunc(pkgs, func(a, b *domain.InstalledPackage) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		if a.Active != b.Active {
+			if a.Active {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.FullVersion(), b.FullVersion())
+	})
+	return pkgs, nil
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
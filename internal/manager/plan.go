@@ -0,0 +1,225 @@
+package manager
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// Plan actions
+const (
+	PlanInstall = "install"
+	PlanUpgrade = "upgrade"
+	PlanSkip    = "skip"
+)
+
+// PlannedPackage describes what installing a package would do without
+// changing anything on disk.
+type PlannedPackage struct {
+	Name             string        `json:"name"`
+	Version          string        `json:"version"`
+	Action           string        `json:"action"`
+	InstalledVersion string        `json:"installed_version,omitempty"`
+	IsDep            bool          `json:"is_dep,omitempty"`
+	IsCask           bool          `json:"is_cask,omitempty"`
+	Cached           bool          `json:"cached"`
+	Size             int64         `json:"size"`
+	Links            []PlannedLink `json:"links,omitempty"`
+	// LinksKnown is false while the archive is not downloaded, or when it
+	// cannot be listed.
+	LinksKnown bool `json:"links_known"`
+}
+
+// PlannedLink is a symlink in the bin or lib directory that would be created.
+// Replaces is set when a link with the same name already points elsewhere.
+type PlannedLink struct {
+	Path     string `json:"path"`
+	Target   string `json:"target"`
+	Replaces string `json:"replaces,omitempty"`
+}
+
+// Plan reports what Install, or Upgrade when upgrade is set, would do
+// for pkg. Install skips packages that are already installed. Size is
+// the download size, -1 when cached or unknown.
+func (m *Manager) Plan(ctx context.Context, pkg domain.Package, upgrade bool) (*PlannedPackage, error) {
+	plan := &PlannedPackage{
+		Name:    pkg.Name,
+		Version: pkg.FullVersion,
+		IsDep:   pkg.IsDep,
+		IsCask:  pkg.IsCask,
+		Action:  PlanInstall,
+		Size:    -1,
+	}
+
+	if _, installed, err := m.state.IsInstalled(pkg.Name); err != nil {
+		return nil, err
+	} else if installed != nil {
+		plan.InstalledVersion = installed.FullVersion()
+		if !upgrade {
+			plan.Action = PlanSkip
+			return plan, nil
+		}
+		plan.Action = PlanUpgrade
+	}
+
+	plan.Cached = m.cache.Has(pkg.Name, pkg.FullVersion)
+	if !plan.Cached {
+		if size, err := m.fetcher.Size(ctx, pkg); err == nil {
+			plan.Size = size
+		}
+		return plan, nil
+	}
+
+	if pkg.IsCask {
+		return plan, nil
+	}
+
+	// A version that is already extracted is read from disk, otherwise
+	// the headers of the cached archive say what it would extract
+	pkgPath := filepath.Join(m.packagesDir, pkg.Name, pkg.FullVersion)
+	var libs, bins []string
+	if _, err := os.Stat(pkgPath); err == nil {
+		libs, bins = findLibraries(pkgPath), findBinaries(pkgPath)
+	} else {
+		entries, err := m.extractor.List(m.cache.GetPath(pkg.Name, pkg.FullVersion))
+		if err != nil {
+			return plan, nil
+		}
+		libs, bins = archiveFiles(entries, pkg.Name+"/"+pkg.FullVersion, pkgPath)
+	}
+
+	for _, libPath := range libs {
+		plan.Links = append(plan.Links, plannedLink(m.libDir, libPath))
+	}
+	for _, binPath := range bins {
+		plan.Links = append(plan.Links, plannedLink(m.binDir, binPath))
+	}
+	plan.LinksKnown = true
+
+	return plan, nil
+}
+
+// archiveFiles is findLibraries and findBinaries for the entries of an
+// archive that extracts the package to prefix. The paths returned are
+// where the files end up once the package is at pkgPath.
+func archiveFiles(entries []domain.ArchiveEntry, prefix, pkgPath string) (libs, bins []string) {
+	files := make(map[string]domain.ArchiveEntry)
+	for _, e := range entries {
+		if rel, ok := strings.CutPrefix(path.Clean(e.Name), prefix+"/"); ok {
+			files[rel] = e
+		}
+	}
+	names := slices.Sorted(maps.Keys(files))
+
+	for _, rel := range names {
+		if path.Dir(rel) == "lib" && !files[rel].Mode.IsDir() && isLibrary(path.Base(rel)) {
+			libs = append(libs, filepath.Join(pkgPath, filepath.FromSlash(rel)))
+		}
+	}
+
+	for _, dir := range []string{"bin", "libexec/bin", "libexec"} {
+		for _, rel := range names {
+			if path.Dir(rel) != dir || files[rel].Mode.IsDir() {
+				continue
+			}
+			if mode, ok := archiveMode(files, rel); ok && isExecutable(path.Base(rel), mode) {
+				bins = append(bins, filepath.Join(pkgPath, filepath.FromSlash(rel)))
+			}
+		}
+		if len(bins) > 0 {
+			break
+		}
+	}
+
+	return libs, bins
+}
+
+// archiveMode follows symlinks inside the archive the way os.Stat would
+// once it is extracted. Links leaving the archive are not followed.
+func archiveMode(files map[string]domain.ArchiveEntry, rel string) (os.FileMode, bool) {
+	for range 40 {
+		e, ok := files[rel]
+		if !ok {
+			return 0, false
+		}
+		if e.Mode&os.ModeSymlink == 0 {
+			return e.Mode, true
+		}
+		if path.IsAbs(e.Linkname) {
+			return 0, false
+		}
+		rel = path.Join(path.Dir(rel), e.Linkname)
+	}
+	return 0, false
+}
+
+func plannedLink(dir, target string) PlannedLink {
+	link := PlannedLink{
+		Path:   filepath.Join(dir, filepath.Base(target)),
+		Target: target,
+	}
+	if existing, err := os.Readlink(link.Path); err == nil && existing != link.Target {
+		link.Replaces = existing
+	}
+	return link
+}
+
+// PlanRemove returns the packages Remove would delete for name, starting
+// with name itself and followed by the dependencies it would cascade to.
+func (m *Manager) PlanRemove(name string) ([]*domain.InstalledPackage, error) {
+	installed, err := m.state.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := installed[name]; !ok {
+		return nil, nil
+	}
+
+	var removed []*domain.InstalledPackage
+	for _, n := range removalOrder(name, installed) {
+		removed = append(removed, installed[n])
+	}
+	return removed, nil
+}
+
+// removalOrder lists name and every dependency that no remaining
+// package depends on once name is gone, in the order they are removed.
+func removalOrder(name string, installed map[string]*domain.InstalledPackage) []string {
+	remaining := maps.Clone(installed)
+
+	var order []string
+	var visit func(n string)
+	visit = func(n string) {
+		pkg, ok := remaining[n]
+		if !ok {
+			return
+		}
+		delete(remaining, n)
+		order = append(order, n)
+
+		for _, dep := range pkg.Dependencies {
+			if dependedOn(dep, remaining) {
+				continue
+			}
+			visit(dep)
+		}
+	}
+	visit(name)
+
+	return order
+}
+
+func dependedOn(dep string, installed map[string]*domain.InstalledPackage) bool {
+	for _, pkg := range installed {
+		if slices.Contains(pkg.Dependencies, dep) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// fakeCache has the archives named name-version.
+type fakeCache map[string]bool
+
+func (c fakeCache) Has(name, version string) bool       { return c[name+"-"+version] }
+func (c fakeCache) GetPath(name, version string) string { return "" }
+func (c fakeCache) Store(name, version, src string) (string, error) {
+	return "", fmt.Errorf("not supported")
+}
+func (c fakeCache) Size() (int64, error) { return 0, nil }
+func (c fakeCache) Clear() error         { return nil }
+
+// fakeArchive is an extractor that lists its entries for any archive, or
+// fails to when it is nil.
+type fakeArchive []domain.ArchiveEntry
+
+func (a fakeArchive) Extract(src, dst string) error { return fmt.Errorf("not supported") }
+func (a fakeArchive) ExtractApps(src, dst string) ([]string, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (a fakeArchive) List(src string) ([]domain.ArchiveEntry, error) {
+	if a == nil {
+		return nil, fmt.Errorf("not an archive")
+	}
+	return a, nil
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []*domain.InstalledPackage
+		// extracted lists files of jq-1.7 already in the packages directory
+		extracted map[string]os.FileMode
+		// archive lists the cached archive of jq-1.7
+		archive fakeArchive
+		// linked are existing links in the bin directory
+		linked     []string
+		upgrade    bool
+		wantAction string
+		wantLinks  []string
+		linksKnown bool
+	}{
+		{
+			name:       "cached archive cannot be listed",
+			wantAction: PlanInstall,
+		},
+		{
+			name: "cached archive",
+			archive: fakeArchive{
+				{Name: "jq/1.7/", Mode: os.ModeDir | 0755},
+				{Name: "jq/1.7/bin/", Mode: os.ModeDir | 0755},
+				{Name: "jq/1.7/bin/jq", Mode: 0755},
+				{Name: "jq/1.7/bin/README", Mode: 0644},
+				{Name: "jq/1.7/bin/jq-link", Mode: os.ModeSymlink | 0777, Linkname: "jq"},
+				{Name: "jq/1.7/bin/outside", Mode: os.ModeSymlink | 0777, Linkname: "/usr/bin/env"},
+				{Name: "jq/1.7/lib/libjq.so.1", Mode: os.ModeSymlink | 0777, Linkname: "libjq.so.1.0.4"},
+				{Name: "jq/1.7/lib/libjq.so.1.0.4", Mode: 0644},
+				{Name: "jq/1.7/share/man/man1/jq.1", Mode: 0644},
+				{Name: "jq/1.6/bin/jq", Mode: 0755},
+			},
+			wantAction: PlanInstall,
+			wantLinks:  []string{"lib/libjq.so.1", "lib/libjq.so.1.0.4", "bin/jq", "bin/jq-link"},
+			linksKnown: true,
+		},
+		{
+			name: "cached archive with binaries in libexec",
+			archive: fakeArchive{
+				{Name: "./jq/1.7/libexec/bin/jq", Mode: 0755},
+				{Name: "./jq/1.7/libexec/jq.py", Mode: 0755},
+			},
+			wantAction: PlanInstall,
+			wantLinks:  []string{"libexec/bin/jq"},
+			linksKnown: true,
+		},
+		{
+			name:       "already extracted",
+			extracted:  map[string]os.FileMode{"bin/jq": 0755, "bin/README": 0644, "lib/libjq.so.1": 0644},
+			wantAction: PlanInstall,
+			wantLinks:  []string{"lib/libjq.so.1", "bin/jq"},
+			linksKnown: true,
+		},
+		{
+			name:       "replaces a link",
+			extracted:  map[string]os.FileMode{"bin/jq": 0755},
+			linked:     []string{"jq"},
+			wantAction: PlanInstall,
+			wantLinks:  []string{"bin/jq (replaces)"},
+			linksKnown: true,
+		},
+		{
+			name:       "already installed",
+			installed:  []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			extracted:  map[string]os.FileMode{"bin/jq": 0755},
+			wantAction: PlanSkip,
+		},
+		{
+			name:       "upgrade",
+			installed:  []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			extracted:  map[string]os.FileMode{"bin/jq": 0755},
+			upgrade:    true,
+			wantAction: PlanUpgrade,
+			wantLinks:  []string{"bin/jq"},
+			linksKnown: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.installed...)
+			m.cache = fakeCache{"jq-1.7": true}
+			m.extractor = tt.archive
+
+			pkgPath := filepath.Join(m.packagesDir, "jq", "1.7")
+			for file, mode := range tt.extracted {
+				path := filepath.Join(pkgPath, file)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.MkdirAll(m.binDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.linked {
+				if err := os.Symlink("elsewhere", filepath.Join(m.binDir, name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			plan, err := m.Plan(context.Background(), domain.Package{Name: "jq", Version: "1.7", FullVersion: "1.7"}, tt.upgrade)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Action != tt.wantAction || plan.LinksKnown != tt.linksKnown {
+				t.Errorf("Plan() action = %s, links known = %v, want %s, %v", plan.Action, plan.LinksKnown, tt.wantAction, tt.linksKnown)
+			}
+
+			var links []string
+			for _, l := range plan.Links {
+				rel, _ := filepath.Rel(pkgPath, l.Target)
+				if filepath.Base(l.Path) != filepath.Base(rel) {
+					t.Errorf("link %s points to %s", l.Path, l.Target)
+				}
+				if l.Replaces != "" {
+					rel += " (replaces)"
+				}
+				links = append(links, rel)
+			}
+			if !slices.Equal(links, tt.wantLinks) {
+				t.Errorf("Plan() links = %q, want %q", links, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestRemovalOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		remove    string
+		installed []*domain.InstalledPackage
+		want      []string
+	}{
+		{
+			name:   "not installed",
+			remove: "jq",
+		},
+		{
+			name:   "no dependencies",
+			remove: "jq",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq"},
+			},
+			want: []string{"jq"},
+		},
+		{
+			name:   "cascades to dependencies",
+			remove: "git",
+			installed: []*domain.InstalledPackage{
+				{Name: "git", Dependencies: []string{"libgit2"}},
+				{Name: "libgit2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "openssl", IsDep: true},
+			},
+			want: []string{"git", "libgit2", "openssl"},
+		},
+		{
+			name:   "keeps dependencies others need",
+			remove: "git",
+			installed: []*domain.InstalledPackage{
+				{Name: "git", Dependencies: []string{"libgit2", "openssl"}},
+				{Name: "libgit2", IsDep: true},
+				{Name: "curl", Dependencies: []string{"openssl"}},
+				{Name: "openssl", IsDep: true},
+			},
+			want: []string{"git", "libgit2"},
+		},
+		{
+			name:   "keeps dependencies of dependencies others need",
+			remove: "git",
+			installed: []*domain.InstalledPackage{
+				{Name: "git", Dependencies: []string{"libgit2"}},
+				{Name: "libgit2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "curl", Dependencies: []string{"openssl"}},
+				{Name: "openssl", IsDep: true},
+			},
+			want: []string{"git", "libgit2"},
+		},
+		{
+			name:   "dependency shared within the removed closure",
+			remove: "app",
+			installed: []*domain.InstalledPackage{
+				{Name: "app", Dependencies: []string{"a", "b"}},
+				{Name: "a", IsDep: true, Dependencies: []string{"c"}},
+				{Name: "b", IsDep: true, Dependencies: []string{"c"}},
+				{Name: "c", IsDep: true},
+			},
+			want: []string{"app", "a", "b", "c"},
+		},
+		{
+			name:   "dependency cycle is left to autoremove",
+			remove: "app",
+			installed: []*domain.InstalledPackage{
+				{Name: "app", Dependencies: []string{"a"}},
+				{Name: "a", IsDep: true, Dependencies: []string{"b"}},
+				{Name: "b", IsDep: true, Dependencies: []string{"a"}},
+			},
+			want: []string{"app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installed := make(map[string]*domain.InstalledPackage, len(tt.installed))
+			for _, pkg := range tt.installed {
+				installed[pkg.Name] = pkg
+			}
+
+			got := removalOrder(tt.remove, installed)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("removalOrder(%q) = %v, want %v", tt.remove, got, tt.want)
+			}
+			if len(installed) != len(tt.installed) {
+				t.Errorf("removalOrder changed the installed map")
+			}
+		})
+	}
+}
+
+func TestPlanRemove(t *testing.T) {
+	m := newTestManager(t,
+		&domain.InstalledPackage{Name: "git", Dependencies: []string{"libgit2"}},
+		&domain.InstalledPackage{Name: "libgit2", IsDep: true},
+	)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "git", want: []string{"git", "libgit2"}},
+		{name: "libgit2", want: []string{"libgit2"}},
+		{name: "jq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, err := m.PlanRemove(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, pkg := range planned {
+				got = append(got, pkg.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PlanRemove(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	// Planning leaves everything installed
+	installed, err := m.ListInstalled()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed) != 2 {
+		t.Errorf("PlanRemove removed packages, %d left installed", len(installed))
+	}
+}
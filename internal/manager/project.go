@@ -0,0 +1,243 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// Provision makes pkg available in the packages directory without
+// installing it, for project toolchains. No binaries or libraries are
+// linked. The directory is shared with every project and the global
+// install, so it gets the same rpath an installed package does; the lib
+// directories of the dependency versions a project uses are set by the
+// wrappers LinkFarm writes. It returns the package path.
+func (m *Manager) Provision(ctx context.Context, pkg domain.Package) (_ string, err error) {
+	pkgPath := filepath.Join(m.packagesDir, pkg.Name, pkg.FullVersion)
+	// Extraction is staged, so the directory only exists once complete
+	if _, err := os.Stat(pkgPath); err == nil {
+		return pkgPath, nil
+	}
+
+	defer m.finish(pkg.Name, time.Now(), &err)
+
+	if pkg.IsCask {
+		return "", fmt.Errorf("casks cannot be used in projects")
+	}
+
+	archivePath, err := m.archive(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.extract(archivePath, pkgPath); err != nil {
+		return "", err
+	}
+
+	for _, libPath := range findLibraries(pkgPath) {
+		patchRpath(libPath, m.libDir)
+	}
+	for _, binPath := range findBinaries(pkgPath) {
+		patchRpath(binPath, m.libDir)
+	}
+
+	return pkgPath, nil
+}
+
+// SetProjectPackages records the package versions the project in root
+// uses. Their directories are kept when the same versions are removed
+// globally. With prune set they replace what the project used before,
+// projects whose directory is gone are forgotten, and versions that no
+// project uses anymore and that are not installed are deleted. Without
+// it they are only added. It returns the deleted versions as
+// name-version.
+func (m *Manager) SetProjectPackages(root string, pkgs []domain.Package, prune bool) ([]string, error) {
+	before, err := m.state.ProjectPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var used []domain.ProjectPackage
+	if !prune {
+		for _, p := range before {
+			if p.Project == root {
+				used = append(used, p)
+			}
+		}
+	}
+	for _, pkg := range pkgs {
+		used = append(used, domain.ProjectPackage{Project: root, Name: pkg.Name, FullVersion: pkg.FullVersion})
+	}
+	if err := m.state.SetProjectPackages(root, used); err != nil {
+		return nil, err
+	}
+	if !prune {
+		return nil, nil
+	}
+
+	var released []domain.ProjectPackage
+	for _, p := range before {
+		if p.Project == root {
+			released = append(released, p)
+			continue
+		}
+		if _, err := os.Stat(p.Project); os.IsNotExist(err) {
+			if err := m.state.SetProjectPackages(p.Project, nil); err != nil {
+				return nil, err
+			}
+			released = append(released, p)
+		}
+	}
+
+	var deleted []string
+	for _, p := range released {
+		if keep, err := m.keepPackageDir(p.Name, p.FullVersion); err != nil || keep {
+			continue
+		}
+		path := filepath.Join(m.packagesDir, p.Name, p.FullVersion)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := m.removePackageDir(p.Name, p.FullVersion); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, p.Name+"-"+p.FullVersion)
+	}
+	return deleted, nil
+}
+
+// keepPackageDir reports whether the directory of a package version is
+// still needed, because it is installed or a project uses it.
+func (m *Manager) keepPackageDir(name, version string) (bool, error) {
+	versions, err := m.state.Versions(name)
+	if err != nil {
+		return false, err
+	}
+	if slices.ContainsFunc(versions, func(v *domain.InstalledPackage) bool {
+		return v.FullVersion() == version
+	}) {
+		return true, nil
+	}
+	return m.usedByProject(name, version)
+}
+
+// usedByProject reports whether a project uses a package version.
+func (m *Manager) usedByProject(name, version string) (bool, error) {
+	pkgs, err := m.state.ProjectPackages()
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(pkgs, func(p domain.ProjectPackage) bool {
+		return p.Name == name && p.FullVersion == version
+	}), nil
+}
+
+// FarmTool is a project tool for LinkFarm: the path Provision returned
+// for it and the packages it depends on in the project.
+type FarmTool struct {
+	Path string
+	Deps []domain.Package
+}
+
+// wrapperHeader starts the scripts LinkFarm writes for tools with
+// dependencies.
+const wrapperHeader = "#!/bin/sh\n# Written by chatr project install\n"
+
+// LinkFarm points the links in binDir at the binaries of tools, the same
+// way the global bin directory is linked. Binaries of tools with
+// dependencies get a wrapper script instead, which puts the lib
+// directories of those dependency versions on the library path before
+// running the binary. Links and wrappers left from an earlier run are
+// removed first. It returns the names of the linked binaries.
+func (m *Manager) LinkFarm(binDir string, tools []FarmTool) ([]string, error) {
+	entries, err := os.ReadDir(binDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		path := filepath.Join(binDir, e.Name())
+		if _, ok := FarmTarget(path); ok {
+			os.Remove(path)
+		}
+	}
+
+	var binaries []string
+	for _, tool := range tools {
+		libDirs := make([]string, 0, len(tool.Deps))
+		for _, dep := range tool.Deps {
+			libDirs = append(libDirs, filepath.Join(m.packagesDir, dep.Name, dep.FullVersion, "lib"))
+		}
+
+		for _, binPath := range findBinaries(tool.Path) {
+			binName := filepath.Base(binPath)
+			if len(libDirs) == 0 {
+				err = m.createSymlink(binDir, binPath, binName)
+			} else {
+				err = writeWrapper(filepath.Join(binDir, binName), binPath, libDirs)
+			}
+			if err != nil {
+				return nil, err
+			}
+			binaries = append(binaries, binName)
+		}
+	}
+	return binaries, nil
+}
+
+// writeWrapper writes a script to path that runs target with libDirs
+// first on the library path.
+func writeWrapper(path, target string, libDirs []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	env := "LD_LIBRARY_PATH"
+	if runtime.GOOS == "darwin" {
+		env = "DYLD_LIBRARY_PATH"
+	}
+
+	var b strings.Builder
+	b.WriteString(wrapperHeader)
+	fmt.Fprintf(&b, "%[1]s=%[2]s${%[1]s:+:$%[1]s}\n", env, shellQuote(strings.Join(libDirs, ":")))
+	fmt.Fprintf(&b, "export %s\n", env)
+	fmt.Fprintf(&b, "exec %s \"$@\"\n", shellQuote(target))
+
+	os.Remove(path)
+	return os.WriteFile(path, []byte(b.String()), 0755)
+}
+
+// FarmTarget returns the binary a link or wrapper written by LinkFarm
+// runs. ok is false if path is neither.
+func FarmTarget(path string) (target string, ok bool) {
+	if target, err := os.Readlink(path); err == nil {
+		return target, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), wrapperHeader) {
+		return "", false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		quoted, found := strings.CutPrefix(line, "exec ")
+		if !found {
+			continue
+		}
+		quoted = strings.TrimSuffix(quoted, ` "$@"`)
+		if len(quoted) < 2 || quoted[0] != '\'' || quoted[len(quoted)-1] != '\'' {
+			return "", false
+		}
+		return strings.ReplaceAll(quoted[1:len(quoted)-1], `'\''`, "'"), true
+	}
+	return "", false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
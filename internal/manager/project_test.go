@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// writeBinary creates an executable called name in the bin directory of
+// pkgPath and returns its path.
+func writeBinary(t *testing.T, pkgPath, name string) string {
+	t.Helper()
+
+	path := filepath.Join(pkgPath, "bin", name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLinkFarm(t *testing.T) {
+	m := newTestManager(t)
+	binDir := filepath.Join(t.TempDir(), "it's", "bin")
+
+	jq := filepath.Join(m.packagesDir, "jq", "1.7_1")
+	jqBin := writeBinary(t, jq, "jq")
+	fd := filepath.Join(m.packagesDir, "fd", "9.0")
+	fdBin := writeBinary(t, fd, "fd")
+
+	// Left from an earlier run
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeWrapper(filepath.Join(binDir, "rg"), "/gone/rg", []string{"/gone/lib"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "notes"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaries, err := m.LinkFarm(binDir, []FarmTool{
+		{Path: jq, Deps: []domain.Package{{Name: "oniguruma", FullVersion: "6.9.9"}}},
+		{Path: fd},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"jq", "fd"}; !slices.Equal(binaries, want) {
+		t.Errorf("LinkFarm() = %q, want %q", binaries, want)
+	}
+
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"fd", "jq", "notes"}; !slices.Equal(names, want) {
+		t.Errorf("bin directory = %q, want %q", names, want)
+	}
+
+	if target, err := os.Readlink(filepath.Join(binDir, "fd")); err != nil || target != fdBin {
+		t.Errorf("fd links to %q, %v, want %q", target, err, fdBin)
+	}
+
+	wrapper := filepath.Join(binDir, "jq")
+	if _, err := os.Readlink(wrapper); err == nil {
+		t.Errorf("jq is a link, want a wrapper")
+	}
+	if target, ok := FarmTarget(wrapper); !ok || target != jqBin {
+		t.Errorf("FarmTarget(jq) = %q, %v, want %q", target, ok, jqBin)
+	}
+	data, err := os.ReadFile(wrapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lib := filepath.Join(m.packagesDir, "oniguruma", "6.9.9", "lib"); !strings.Contains(string(data), lib) {
+		t.Errorf("wrapper does not put %s on the library path:\n%s", lib, data)
+	}
+
+	if _, ok := FarmTarget(filepath.Join(binDir, "notes")); ok {
+		t.Errorf("FarmTarget(notes) = true, want false for a file chatr did not write")
+	}
+}
+
+func TestSetProjectPackages(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []*domain.InstalledPackage
+		// before is what each project used, the project "gone" has no
+		// directory anymore
+		before      []domain.ProjectPackage
+		pkgs        []domain.Package
+		prune       bool
+		want        []string
+		wantDeleted []string
+	}{
+		{
+			name:   "without prune versions are added",
+			before: []domain.ProjectPackage{{Project: "a", Name: "jq", FullVersion: "1.6"}},
+			pkgs:   []domain.Package{{Name: "fd", FullVersion: "9.0"}},
+			want:   []string{"a fd 9.0", "a jq 1.6"},
+		},
+		{
+			name:   "without prune gone projects are kept",
+			before: []domain.ProjectPackage{{Project: "gone", Name: "jq", FullVersion: "1.6"}},
+			pkgs:   []domain.Package{{Name: "jq", FullVersion: "1.7"}},
+			want:   []string{"a jq 1.7", "gone jq 1.6"},
+		},
+		{
+			name:        "prune replaces and deletes unused versions",
+			before:      []domain.ProjectPackage{{Project: "a", Name: "jq", FullVersion: "1.6"}},
+			pkgs:        []domain.Package{{Name: "jq", FullVersion: "1.7"}},
+			prune:       true,
+			want:        []string{"a jq 1.7"},
+			wantDeleted: []string{"jq-1.6"},
+		},
+		{
+			name:      "prune keeps installed versions",
+			installed: []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			before:    []domain.ProjectPackage{{Project: "a", Name: "jq", FullVersion: "1.6"}},
+			prune:     true,
+		},
+		{
+			name: "prune keeps versions another project uses",
+			before: []domain.ProjectPackage{
+				{Project: "a", Name: "jq", FullVersion: "1.6"},
+				{Project: "b", Name: "jq", FullVersion: "1.6"},
+			},
+			prune: true,
+			want:  []string{"b jq 1.6"},
+		},
+		{
+			name: "prune forgets projects whose directory is gone",
+			before: []domain.ProjectPackage{
+				{Project: "a", Name: "jq", FullVersion: "1.7"},
+				{Project: "gone", Name: "fd", FullVersion: "9.0"},
+				{Project: "gone", Name: "jq", FullVersion: "1.7"},
+			},
+			pkgs:        []domain.Package{{Name: "jq", FullVersion: "1.7"}},
+			prune:       true,
+			want:        []string{"a jq 1.7"},
+			wantDeleted: []string{"fd-9.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.installed...)
+
+			projects := t.TempDir()
+			for _, name := range []string{"a", "b"} {
+				if err := os.Mkdir(filepath.Join(projects, name), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			roots := make(map[string]string)
+			for _, p := range tt.before {
+				roots[filepath.Join(projects, p.Project)] = p.Project
+			}
+
+			dirs := make(map[string]bool)
+			for _, pkg := range tt.installed {
+				dirs[pkg.Name+"-"+pkg.FullVersion()] = true
+			}
+			for _, p := range tt.before {
+				dirs[p.Name+"-"+p.FullVersion] = true
+			}
+			for root := range roots {
+				var used []domain.ProjectPackage
+				for _, p := range tt.before {
+					if filepath.Join(projects, p.Project) == root {
+						p.Project = root
+						used = append(used, p)
+						if err := os.MkdirAll(filepath.Join(m.packagesDir, p.Name, p.FullVersion), 0755); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+				if err := m.state.SetProjectPackages(root, used); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			root := filepath.Join(projects, "a")
+			roots[root] = "a"
+			deleted, err := m.SetProjectPackages(root, tt.pkgs, tt.prune)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(deleted, tt.wantDeleted) {
+				t.Errorf("SetProjectPackages() deleted %q, want %q", deleted, tt.wantDeleted)
+			}
+
+			recorded, err := m.state.ProjectPackages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range recorded {
+				got = append(got, roots[p.Project]+" "+p.Name+" "+p.FullVersion)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("project packages = %q, want %q", got, tt.want)
+			}
+
+			for dir := range dirs {
+				name, version, _ := strings.Cut(dir, "-")
+				_, err := os.Stat(filepath.Join(m.packagesDir, name, version))
+				if exists, wantDeleted := err == nil, slices.Contains(tt.wantDeleted, dir); exists == wantDeleted {
+					t.Errorf("%s exists = %v, want %v", dir, exists, !wantDeleted)
+				}
+			}
+		})
+	}
+}
+
+func TestRemovePackageDir(t *testing.T) {
+	m := newTestManager(t)
+	for _, v := range []string{"1.6", "1.7"} {
+		if err := os.MkdirAll(filepath.Join(m.packagesDir, "jq", v), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.state.SetProjectPackages(t.TempDir(), []domain.ProjectPackage{{Name: "jq", FullVersion: "1.7"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"1.6", "1.7"} {
+		if err := m.removePackageDir("jq", v); err != nil {
+			t.Fatalf("removePackageDir(jq, %s) error = %v", v, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(m.packagesDir, "jq", "1.6")); !os.IsNotExist(err) {
+		t.Errorf("jq 1.6 was kept, want it removed")
+	}
+	if _, err := os.Stat(filepath.Join(m.packagesDir, "jq", "1.7")); err != nil {
+		t.Errorf("jq 1.7 was removed, want it kept for the project: %v", err)
+	}
+}
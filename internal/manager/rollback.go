@@ -0,0 +1,294 @@
+package manager
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// RollbackStep restores one package to the version it had before the
+// rolled back transactions. An empty From means the package has to be
+// installed again, an empty To that it has to be removed. Inactive steps
+// only install or remove a version kept next to the active one.
+type RollbackStep struct {
+	Name     string `json:"name"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	IsDep    bool   `json:"is_dep,omitempty"`
+	IsCask   bool   `json:"is_cask,omitempty"`
+	Inactive bool   `json:"inactive,omitempty"`
+	// Cached is false when To is neither installed nor in the cache
+	Cached bool `json:"cached"`
+
+	change domain.PackageChange
+}
+
+// PlanRollback returns the steps that undo every transaction with an ID
+// greater than after. Only changes that succeeded are undone.
+func (m *Manager) PlanRollback(after int64) ([]RollbackStep, error) {
+	transactions, err := m.state.Transactions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Transactions are newest first, so the change kept for a package
+	// is the oldest one and describes it before any of them ran.
+	// Versions kept next to the active one are tracked on their own.
+	type versionKey struct{ name, version string }
+	before := make(map[string]domain.PackageChange)
+	inactive := make(map[versionKey]domain.PackageChange)
+	for _, t := range transactions {
+		if t.ID <= after {
+			continue
+		}
+		for _, c := range slices.Backward(t.Changes) {
+			if c.Outcome != domain.OutcomeSuccess || c.Action == domain.ActionReinstall {
+				continue
+			}
+			if c.Inactive {
+				v := versionKey{c.Name, c.OldVersion}
+				if c.Action == domain.ActionInstall {
+					v.version = c.NewVersion
+				}
+				inactive[v] = c
+				continue
+			}
+			before[c.Name] = c
+		}
+	}
+
+	installed, err := m.state.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []RollbackStep
+	for name, c := range before {
+		step := RollbackStep{
+			Name:   name,
+			To:     c.OldVersion,
+			IsDep:  c.IsDep,
+			IsCask: c.IsCask,
+			Cached: true,
+			change: c,
+		}
+		if pkg, ok := installed[name]; ok {
+			step.From = pkg.FullVersion()
+		}
+		if step.From == step.To {
+			continue
+		}
+		if step.To != "" {
+			step.Cached, err = m.restorable(name, step.To)
+			if err != nil {
+				return nil, err
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	for v, c := range inactive {
+		name, version := v.name, v.version
+		// Removing the package takes every version with it
+		if b, ok := before[name]; ok && b.OldVersion == "" {
+			continue
+		}
+
+		versions, err := m.state.Versions(name)
+		if err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(versions, func(v *domain.InstalledPackage) bool {
+			return v.FullVersion() == version
+		})
+
+		step := RollbackStep{Name: name, IsDep: c.IsDep, Inactive: true, Cached: true, change: c}
+		switch {
+		case c.Action == domain.ActionRemove && i < 0:
+			step.To = version
+			step.Cached = m.cache.Has(name, version)
+		case c.Action == domain.ActionInstall && i >= 0 && !versions[i].Active:
+			step.From = version
+		default:
+			continue
+		}
+		steps = append(steps, step)
+	}
+
+	// Removals go first so restored packages can take over their links,
+	// then versions kept aside, which restores may switch to
+	slices.SortFunc(steps, func(a, b RollbackStep) int {
+		if c := cmp.Compare(stepOrder(a), stepOrder(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return steps, nil
+}
+
+func stepOrder(step RollbackStep) int {
+	switch {
+	case step.To == "" && !step.Inactive:
+		return 0
+	case step.Inactive:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// restorable reports whether version of a package can be restored,
+// either because it is still installed next to the active one or
+// because its archive is cached.
+func (m *Manager) restorable(name, version string) (bool, error) {
+	versions, err := m.state.Versions(name)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range versions {
+		if v.FullVersion() == version {
+			return true, nil
+		}
+	}
+	return m.cache.Has(name, version), nil
+}
+
+// Rollback applies steps from PlanRollback. It refuses to start if a
+// version to restore is neither installed nor cached. done is called
+// after each step with its error, if any.
+func (m *Manager) Rollback(ctx context.Context, steps []RollbackStep, done func(step RollbackStep, err error)) error {
+	var missing []string
+	for _, step := range steps {
+		if !step.Cached {
+			missing = append(missing, step.Name+"-"+step.To)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cannot roll back, archives no longer in the cache: %s", strings.Join(missing, ", "))
+	}
+
+	for _, step := range steps {
+		done(step, m.rollbackStep(ctx, step))
+	}
+	return nil
+}
+
+func (m *Manager) rollbackStep(ctx context.Context, step RollbackStep) error {
+	if step.Inactive {
+		return m.rollbackInactive(step)
+	}
+
+	_, current, err := m.state.IsInstalled(step.Name)
+	if err != nil {
+		return err
+	}
+
+	if step.To == "" {
+		if current == nil {
+			return nil
+		}
+		return m.removeOne(current)
+	}
+
+	version, revision := domain.SplitVersion(step.To)
+	pkg := domain.Package{
+		Name:        step.Name,
+		Version:     version,
+		Revision:    revision,
+		FullVersion: step.To,
+		DownloadURL: step.change.OldURL,
+		IsDep:       step.IsDep,
+		IsCask:      step.IsCask,
+	}
+
+	if _, err := m.Restore(ctx, pkg); err != nil {
+		return err
+	}
+
+	return m.SetDependencies(step.Name, step.change.Dependencies)
+}
+
+// rollbackInactive brings back or removes a version kept next to the
+// active one, without touching the active version.
+func (m *Manager) rollbackInactive(step RollbackStep) error {
+	if step.To != "" {
+		version, revision := domain.SplitVersion(step.To)
+		return m.restoreVersion(domain.Package{
+			Name:        step.Name,
+			Version:     version,
+			Revision:    revision,
+			FullVersion: step.To,
+			DownloadURL: step.change.OldURL,
+			IsDep:       step.IsDep,
+		}, step.change.Dependencies)
+	}
+
+	active, match, err := m.findVersion(step.Name, step.From)
+	if err != nil {
+		return err
+	}
+	if match == active {
+		return fmt.Errorf("%s %s is the active version", step.Name, step.From)
+	}
+	return m.removeVersion(match)
+}
+
+// Restore installs pkg from its cached archive, replacing the installed
+// version if there is one. Unlike Upgrade it can go back to an older
+// version that is no longer in the registry. A version that is still
+// installed next to the active one is switched to instead.
+func (m *Manager) Restore(ctx context.Context, pkg domain.Package) (*domain.InstalledPackage, error) {
+	if _, match, err := m.findVersion(pkg.Name, pkg.FullVersion); err == nil && !match.IsCask && match.FullVersion() == pkg.FullVersion {
+		return m.Switch(pkg.Name, pkg.FullVersion)
+	}
+
+	if !m.cache.Has(pkg.Name, pkg.FullVersion) {
+		return nil, fmt.Errorf("%s-%s is not in the cache", pkg.Name, pkg.FullVersion)
+	}
+
+	_, current, err := m.state.IsInstalled(pkg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return m.Install(ctx, pkg)
+	}
+
+	old := domain.Package{Name: current.Name, FullVersion: current.FullVersion()}
+	return m.Upgrade(ctx, old, pkg)
+}
+
+// CachedPackage returns the package to install a cached version from.
+// It does not check the cache, Restore refuses versions that are not in
+// it. Like rollback, it takes the download URL from the last change away
+// from that version, so the installed package records where it came from
+// again.
+func (m *Manager) CachedPackage(name, fullVersion string) (domain.Package, error) {
+	version, revision := domain.SplitVersion(fullVersion)
+	pkg := domain.Package{
+		Name:        name,
+		Version:     version,
+		Revision:    revision,
+		FullVersion: fullVersion,
+	}
+
+	transactions, err := m.state.Transactions()
+	if err != nil {
+		return pkg, err
+	}
+	for _, t := range transactions {
+		for _, c := range t.Changes {
+			if c.Name == name && c.OldVersion == fullVersion && c.OldURL != "" {
+				pkg.DownloadURL = c.OldURL
+				return pkg, nil
+			}
+		}
+	}
+	return pkg, nil
+}
@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func change(action, name, oldVersion, newVersion string) domain.PackageChange {
+	return domain.PackageChange{
+		Name:       name,
+		Action:     action,
+		OldVersion: oldVersion,
+		NewVersion: newVersion,
+		Outcome:    domain.OutcomeSuccess,
+	}
+}
+
+func TestPlanRollback(t *testing.T) {
+	failed := change(domain.ActionUpgrade, "jq", "1.6", "1.7")
+	failed.Outcome = domain.OutcomeFailed
+
+	inactiveRemove := change(domain.ActionRemove, "jq", "1.6", "")
+	inactiveRemove.Inactive = true
+	inactiveInstall := change(domain.ActionInstall, "jq", "", "1.6")
+	inactiveInstall.Inactive = true
+
+	tests := []struct {
+		name      string
+		installed []*domain.InstalledPackage
+		// kept are installed next to the active version
+		kept   []*domain.InstalledPackage
+		cached []string
+		// transactions are oldest first, numbered from 1
+		transactions [][]domain.PackageChange
+		after        int64
+		want         []string
+	}{
+		{
+			name:         "nothing to undo",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			transactions: [][]domain.PackageChange{{change(domain.ActionInstall, "jq", "", "1.7")}},
+			after:        1,
+		},
+		{
+			name:         "install is removed",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			transactions: [][]domain.PackageChange{{change(domain.ActionInstall, "jq", "", "1.7")}},
+			want:         []string{"jq 1.7 -> "},
+		},
+		{
+			name:         "upgrade is restored from the cache",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			cached:       []string{"jq-1.6"},
+			transactions: [][]domain.PackageChange{{change(domain.ActionUpgrade, "jq", "1.6", "1.7")}},
+			want:         []string{"jq 1.7 -> 1.6"},
+		},
+		{
+			name:         "upgrade without the old archive",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			transactions: [][]domain.PackageChange{{change(domain.ActionUpgrade, "jq", "1.6", "1.7")}},
+			want:         []string{"jq 1.7 -> 1.6 (not cached)"},
+		},
+		{
+			name:         "upgrade that kept the old version",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			kept:         []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			transactions: [][]domain.PackageChange{{change(domain.ActionUpgrade, "jq", "1.6", "1.7")}},
+			want:         []string{"jq 1.7 -> 1.6"},
+		},
+		{
+			name:         "removal is installed again",
+			cached:       []string{"jq-1.7"},
+			transactions: [][]domain.PackageChange{{change(domain.ActionRemove, "jq", "1.7", "")}},
+			want:         []string{"jq  -> 1.7"},
+		},
+		{
+			name:      "oldest change of a package wins",
+			installed: []*domain.InstalledPackage{{Name: "jq", Version: "1.8"}},
+			cached:    []string{"jq-1.6"},
+			transactions: [][]domain.PackageChange{
+				{change(domain.ActionUpgrade, "jq", "1.6", "1.7")},
+				{change(domain.ActionUpgrade, "jq", "1.7", "1.8")},
+			},
+			want: []string{"jq 1.8 -> 1.6"},
+		},
+		{
+			name:      "transactions up to after are kept",
+			installed: []*domain.InstalledPackage{{Name: "jq", Version: "1.8"}},
+			cached:    []string{"jq-1.6", "jq-1.7"},
+			transactions: [][]domain.PackageChange{
+				{change(domain.ActionUpgrade, "jq", "1.6", "1.7")},
+				{change(domain.ActionUpgrade, "jq", "1.7", "1.8")},
+			},
+			after: 1,
+			want:  []string{"jq 1.8 -> 1.7"},
+		},
+		{
+			name:         "failed changes and reinstalls are skipped",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			transactions: [][]domain.PackageChange{{failed, change(domain.ActionReinstall, "jq", "1.6", "1.6")}},
+		},
+		{
+			name:         "inactive version is restored",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			cached:       []string{"jq-1.6"},
+			transactions: [][]domain.PackageChange{{inactiveRemove}},
+			want:         []string{"jq  -> 1.6 (inactive)"},
+		},
+		{
+			name:         "inactive version is removed",
+			installed:    []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			kept:         []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			transactions: [][]domain.PackageChange{{inactiveInstall}},
+			want:         []string{"jq 1.6 ->  (inactive)"},
+		},
+		{
+			name:      "inactive versions go with their package",
+			installed: []*domain.InstalledPackage{{Name: "jq", Version: "1.7"}},
+			kept:      []*domain.InstalledPackage{{Name: "jq", Version: "1.6"}},
+			transactions: [][]domain.PackageChange{
+				{change(domain.ActionInstall, "jq", "", "1.7")},
+				{inactiveInstall},
+			},
+			want: []string{"jq 1.7 -> "},
+		},
+		{
+			name: "removals first, then inactive versions, then restores",
+			installed: []*domain.InstalledPackage{
+				{Name: "fd", Version: "9.0"},
+				{Name: "jq", Version: "1.7"},
+				{Name: "rg", Version: "14.0"},
+			},
+			cached: []string{"bat-0.24", "jq-1.6", "rg-13.0"},
+			transactions: [][]domain.PackageChange{{
+				change(domain.ActionRemove, "bat", "0.24", ""),
+				change(domain.ActionUpgrade, "rg", "13.0", "14.0"),
+				inactiveRemove,
+				change(domain.ActionInstall, "fd", "", "9.0"),
+			}},
+			want: []string{"fd 9.0 -> ", "jq  -> 1.6 (inactive)", "bat  -> 0.24", "rg 14.0 -> 13.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.installed...)
+			for _, pkg := range tt.kept {
+				if err := m.state.AddVersion(pkg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cache := fakeCache{}
+			for _, archive := range tt.cached {
+				cache[archive] = true
+			}
+			m.cache = cache
+
+			for _, changes := range tt.transactions {
+				err := m.state.BeginTransaction(&domain.Transaction{
+					Time:    time.Now(),
+					Command: "chatr test",
+					Outcome: domain.OutcomeSuccess,
+					Changes: changes,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			steps, err := m.PlanRollback(tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, step := range steps {
+				s := fmt.Sprintf("%s %s -> %s", step.Name, step.From, step.To)
+				if step.Inactive {
+					s += " (inactive)"
+				}
+				if !step.Cached {
+					s += " (not cached)"
+				}
+				got = append(got, s)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PlanRollback(%d) = %q, want %q", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedPackage(t *testing.T) {
+	upgrade := change(domain.ActionUpgrade, "jq", "1.6_1", "1.7")
+	upgrade.OldURL = "https://example.com/jq-1.6_1.tar.gz"
+
+	tests := []struct {
+		name        string
+		changes     []domain.PackageChange
+		fullVersion string
+		want        domain.Package
+	}{
+		{
+			name:        "unknown version",
+			fullVersion: "1.5",
+			want:        domain.Package{Name: "jq", Version: "1.5", FullVersion: "1.5"},
+		},
+		{
+			name:        "URL from the history",
+			changes:     []domain.PackageChange{upgrade},
+			fullVersion: "1.6_1",
+			want:        domain.Package{Name: "jq", Version: "1.6", Revision: "1", FullVersion: "1.6_1", DownloadURL: "https://example.com/jq-1.6_1.tar.gz"},
+		},
+		{
+			name:        "history of another version",
+			changes:     []domain.PackageChange{upgrade},
+			fullVersion: "1.6",
+			want:        domain.Package{Name: "jq", Version: "1.6", FullVersion: "1.6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, &domain.InstalledPackage{Name: "jq", Version: "1.7", URL: "https://example.com/jq-1.7.tar.gz"})
+			if tt.changes != nil {
+				err := m.state.BeginTransaction(&domain.Transaction{
+					Time:    time.Now(),
+					Command: "chatr test",
+					Outcome: domain.OutcomeSuccess,
+					Changes: tt.changes,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := m.CachedPackage("jq", tt.fullVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CachedPackage(jq, %s) = %+v, want %+v", tt.fullVersion, got, tt.want)
+			}
+		})
+	}
+}
@@ -9,20 +9,29 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/teamcutter/chatr/internal/domain"
 )
 
+// stagingPrefix starts the names of the directories archives are
+// extracted into before they are moved into the packages directory.
+const stagingPrefix = ".staging-"
+
 type Manager struct {
 	fetcher     domain.Fetcher
 	cache       domain.Cache
 	extractor   domain.Extractor
 	state       domain.State
+	events      domain.EventSink
 	packagesDir string
 	binDir      string
 	libDir      string
 	appsDir     string
+
+	txMu sync.Mutex
+	tx   *domain.Transaction
 }
 
 func New(
@@ -30,6 +39,7 @@ func New(
 	cache domain.Cache,
 	extractor domain.Extractor,
 	state domain.State,
+	events domain.EventSink,
 	packagesDir, binDir, libDir, appsDir string,
 ) *Manager {
 
@@ -38,6 +48,7 @@ func New(
 		cache:       cache,
 		extractor:   extractor,
 		state:       state,
+		events:      events,
 		packagesDir: packagesDir,
 		binDir:      binDir,
 		libDir:      libDir,
@@ -45,25 +56,25 @@ func New(
 	}
 }
 
-func (m *Manager) Install(ctx context.Context, pkg domain.Package) (*domain.InstalledPackage, error) {
+func (m *Manager) Install(ctx context.Context, pkg domain.Package) (_ *domain.InstalledPackage, err error) {
+	defer m.finish(pkg.Name, time.Now(), &err)
+
 	if installed, _, _ := m.state.IsInstalled(pkg.Name); installed {
 		return nil, fmt.Errorf("package %s already installed", pkg.Name)
 	}
 
-	var archivePath string
-	if m.cache.Has(pkg.Name, pkg.FullVersion) {
-		archivePath = m.cache.GetPath(pkg.Name, pkg.FullVersion)
-	} else {
-		result := m.fetcher.Fetch(ctx, pkg)
-		if result.Error != nil {
-			return nil, result.Error
-		}
+	done := m.track(domain.PackageChange{
+		Name:       pkg.Name,
+		Action:     domain.ActionInstall,
+		NewVersion: pkg.FullVersion,
+		IsDep:      pkg.IsDep,
+		IsCask:     pkg.IsCask,
+	})
+	defer func() { done(err) }()
 
-		var err error
-		archivePath, err = m.cache.Store(pkg.Name, pkg.FullVersion, result.Path)
-		if err != nil {
-			return nil, fmt.Errorf("failed to cache %s: %w", pkg.Name, err)
-		}
+	archivePath, err := m.archive(ctx, pkg)
+	if err != nil {
+		return nil, err
 	}
 
 	pkgPath := filepath.Join(m.packagesDir, pkg.Name, pkg.FullVersion)
@@ -83,40 +94,9 @@ func (m *Manager) Install(ctx context.Context, pkg domain.Package) (*domain.Inst
 		return nil, fmt.Errorf("failed to begin install: %w", err)
 	}
 
-	var binaryNames []string
-	var libNames []string
-	var appNames []string
-
-	if pkg.IsCask {
-		apps, err := m.extractor.ExtractApps(archivePath, m.appsDir)
-		if err != nil {
-			return nil, err
-		}
-		appNames = apps
-	} else {
-		// Ensure that if any previous installations
-		// failed, we extract into clear dir
-		os.RemoveAll(pkgPath)
-
-		if err := m.extractor.Extract(archivePath, m.packagesDir); err != nil {
-			return nil, err
-		}
-
-		for _, libPath := range findLibraries(pkgPath) {
-			libName := filepath.Base(libPath)
-			m.createLibSymlink(libPath, libName)
-			patchRpath(libPath, m.libDir)
-			libNames = append(libNames, libName)
-		}
-
-		for _, binPath := range findBinaries(pkgPath) {
-			binName := filepath.Base(binPath)
-			if err := m.createSymlink(binPath, binName); err != nil {
-				return nil, err
-			}
-			patchRpath(binPath, m.libDir)
-			binaryNames = append(binaryNames, binName)
-		}
+	binaryNames, libNames, appNames, err := m.extractAndLink(archivePath, pkgPath, pkg.IsCask)
+	if err != nil {
+		return nil, err
 	}
 
 	installedPkg := &domain.InstalledPackage{
@@ -140,61 +120,129 @@ func (m *Manager) Install(ctx context.Context, pkg domain.Package) (*domain.Inst
 	return installedPkg, nil
 }
 
+// Remove removes every version of a package, and the dependencies only
+// it needed. With pkg.Version set only that version is removed, which
+// must not be the active one while others are installed.
 func (m *Manager) Remove(ctx context.Context, pkg domain.Package) (*domain.InstalledPackage, error) {
-	installed, installedPkg, _ := m.state.IsInstalled(pkg.Name)
-	if !installed {
-		return nil, fmt.Errorf("package %s is not installed", pkg.Name)
-	}
-
-	if installedPkg.IsCask {
-		for _, appName := range installedPkg.Apps {
-			appPath := filepath.Join(m.appsDir, appName)
-			if err := os.RemoveAll(appPath); err != nil {
-				return nil, fmt.Errorf("failed to remove app %s: %w", appName, err)
-			}
+	if pkg.Version != "" {
+		active, match, err := m.findVersion(pkg.Name, pkg.Version)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		for _, binName := range installedPkg.Binaries {
-			os.Remove(filepath.Join(m.binDir, binName))
+		if match != active {
+			return match, m.removeVersion(match)
 		}
-		for _, libName := range installedPkg.Libs {
-			os.Remove(filepath.Join(m.libDir, libName))
+		if versions, _ := m.state.Versions(pkg.Name); len(versions) > 1 {
+			return nil, fmt.Errorf("%s %s is the active version, switch to another one first", pkg.Name, match.FullVersion())
 		}
 	}
 
-	packageDir := filepath.Join(m.packagesDir, pkg.Name)
-	if err := os.RemoveAll(packageDir); err != nil {
+	installed, err := m.state.ListInstalled()
+	if err != nil {
 		return nil, err
 	}
 
-	if err := m.state.Remove(pkg.Name); err != nil {
-		return nil, err
+	installedPkg, ok := installed[pkg.Name]
+	if !ok {
+		return nil, fmt.Errorf("package %s is not installed", pkg.Name)
 	}
 
-	for _, dep := range installedPkg.Dependencies {
-		if m.isDependencyOf(dep, pkg.Name) {
-			continue
+	// Failures while cascading to dependencies are not fatal, the
+	// requested package is already gone at that point
+	for i, name := range removalOrder(pkg.Name, installed) {
+		if err := m.removeOne(installed[name]); err != nil && i == 0 {
+			return nil, err
 		}
-		m.Remove(ctx, domain.Package{Name: dep})
 	}
 
 	return installedPkg, nil
 }
 
-func (m *Manager) isDependencyOf(dep, excludeName string) bool {
+func (m *Manager) removeOne(pkg *domain.InstalledPackage) (err error) {
+	done := m.track(domain.PackageChange{
+		Name:         pkg.Name,
+		Action:       domain.ActionRemove,
+		OldVersion:   pkg.FullVersion(),
+		OldURL:       pkg.URL,
+		Dependencies: pkg.Dependencies,
+		IsDep:        pkg.IsDep,
+		IsCask:       pkg.IsCask,
+	})
+	defer func() { done(err) }()
+
+	if err := m.unlink(pkg); err != nil {
+		return err
+	}
+
+	versions, err := m.state.Versions(pkg.Name)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if err := m.removePackageDir(v.Name, v.FullVersion()); err != nil {
+			return err
+		}
+	}
+
+	return m.state.Remove(pkg.Name)
+}
+
+// Dependents returns the names of installed packages that list dep
+// among their dependencies.
+func (m *Manager) Dependents(dep string) ([]string, error) {
 	installed, err := m.state.ListInstalled()
 	if err != nil {
-		return false
+		return nil, err
 	}
+
+	var names []string
 	for name, pkg := range installed {
-		if name == excludeName {
-			continue
-		}
 		if slices.Contains(pkg.Dependencies, dep) {
-			return true
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Orphans returns dependency packages that are no longer reachable
+// from any package that was installed on request.
+func (m *Manager) Orphans() ([]*domain.InstalledPackage, error) {
+	installed, err := m.state.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+
+	reachable := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if reachable[name] {
+			return
+		}
+		reachable[name] = true
+		if pkg, ok := installed[name]; ok {
+			for _, dep := range pkg.Dependencies {
+				visit(dep)
+			}
+		}
+	}
+
+	for name, pkg := range installed {
+		if !pkg.IsDep {
+			visit(name)
+		}
+	}
+
+	var orphans []*domain.InstalledPackage
+	for name, pkg := range installed {
+		if !reachable[name] {
+			orphans = append(orphans, pkg)
 		}
 	}
-	return false
+	slices.SortFunc(orphans, func(a, b *domain.InstalledPackage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return orphans, nil
 }
 
 func (m *Manager) SetDependencies(name string, deps []string) error {
@@ -206,26 +254,75 @@ func (m *Manager) SetDependencies(name string, deps []string) error {
 	return m.state.Add(pkg)
 }
 
+func (m *Manager) SetPinned(name string, pinned bool) error {
+	_, pkg, err := m.state.IsInstalled(name)
+	if err != nil {
+		return err
+	}
+	if pkg == nil {
+		return fmt.Errorf("package %s is not installed", name)
+	}
+	pkg.Pinned = pinned
+	return m.state.Add(pkg)
+}
+
+// MarkDependency records a package as installed as a dependency, so
+// autoremove takes it once no installed package needs it.
+func (m *Manager) MarkDependency(name string) error {
+	_, pkg, err := m.state.IsInstalled(name)
+	if err != nil {
+		return err
+	}
+	if pkg == nil {
+		return fmt.Errorf("package %s is not installed", name)
+	}
+	pkg.IsDep = true
+	return m.state.Add(pkg)
+}
+
+// Upgrade replaces the active version of a package with newPackage.
+// Other installed versions are left alone.
 func (m *Manager) Upgrade(ctx context.Context, oldPackage domain.Package, newPackage domain.Package) (*domain.InstalledPackage, error) {
+	return m.upgrade(ctx, oldPackage, newPackage, false)
+}
+
+// UpgradeKeep installs newPackage next to the active version and makes
+// it the active one. The old version stays installed, so Switch can go
+// back to it.
+func (m *Manager) UpgradeKeep(ctx context.Context, oldPackage domain.Package, newPackage domain.Package) (*domain.InstalledPackage, error) {
+	return m.upgrade(ctx, oldPackage, newPackage, true)
+}
+
+func (m *Manager) upgrade(ctx context.Context, oldPackage domain.Package, newPackage domain.Package, keep bool) (_ *domain.InstalledPackage, err error) {
+	defer m.finish(newPackage.Name, time.Now(), &err)
+
 	_, oldInstalled, _ := m.state.IsInstalled(oldPackage.Name)
 	var oldDeps []string
+	var pinned bool
 	if oldInstalled != nil {
 		oldDeps = oldInstalled.Dependencies
+		pinned = oldInstalled.Pinned
 	}
 
-	var archivePath string
-	if m.cache.Has(newPackage.Name, newPackage.FullVersion) {
-		archivePath = m.cache.GetPath(newPackage.Name, newPackage.FullVersion)
-	} else {
-		result := m.fetcher.Fetch(ctx, newPackage)
-		if result.Error != nil {
-			return nil, result.Error
-		}
-		var err error
-		archivePath, err = m.cache.Store(newPackage.Name, newPackage.FullVersion, result.Path)
-		if err != nil {
-			return nil, fmt.Errorf("failed to cache %s: %w", newPackage.Name, err)
-		}
+	change := domain.PackageChange{
+		Name:         newPackage.Name,
+		Action:       domain.ActionUpgrade,
+		OldVersion:   oldPackage.FullVersion,
+		NewVersion:   newPackage.FullVersion,
+		Dependencies: oldDeps,
+		IsDep:        newPackage.IsDep,
+		IsCask:       newPackage.IsCask,
+	}
+	if oldInstalled != nil {
+		change.OldVersion = oldInstalled.FullVersion()
+		change.OldURL = oldInstalled.URL
+	}
+	done := m.track(change)
+	defer func() { done(err) }()
+
+	archivePath, err := m.archive(ctx, newPackage)
+	if err != nil {
+		return nil, err
 	}
 
 	pkgPath := filepath.Join(m.packagesDir, newPackage.Name, newPackage.FullVersion)
@@ -239,6 +336,7 @@ func (m *Manager) Upgrade(ctx context.Context, oldPackage domain.Package, newPac
 		Dependencies: oldDeps,
 		IsDep:        newPackage.IsDep,
 		IsCask:       newPackage.IsCask,
+		Pinned:       pinned,
 		InstalledAt:  time.Now(),
 	}
 
@@ -247,53 +345,18 @@ func (m *Manager) Upgrade(ctx context.Context, oldPackage domain.Package, newPac
 	}
 
 	if oldInstalled != nil {
-		if oldInstalled.IsCask {
-			for _, appName := range oldInstalled.Apps {
-				appPath := filepath.Join(m.appsDir, appName)
-				os.RemoveAll(appPath)
-			}
-		} else {
-			for _, binName := range oldInstalled.Binaries {
-				os.Remove(filepath.Join(m.binDir, binName))
-			}
-			for _, libName := range oldInstalled.Libs {
-				os.Remove(filepath.Join(m.libDir, libName))
+		m.unlink(oldInstalled)
+		if !keep && oldInstalled.FullVersion() != newPackage.FullVersion {
+			m.removePackageDir(oldInstalled.Name, oldInstalled.FullVersion())
+			if err := m.state.RemoveVersion(oldInstalled.Name, oldInstalled.FullVersion()); err != nil {
+				return nil, err
 			}
 		}
-		os.RemoveAll(filepath.Join(m.packagesDir, oldPackage.Name))
 	}
 
-	var binaryNames []string
-	var libNames []string
-	var appNames []string
-
-	if newPackage.IsCask {
-		apps, err := m.extractor.ExtractApps(archivePath, m.appsDir)
-		if err != nil {
-			return nil, err
-		}
-		appNames = apps
-	} else {
-		os.RemoveAll(pkgPath)
-		if err := m.extractor.Extract(archivePath, m.packagesDir); err != nil {
-			return nil, err
-		}
-
-		for _, libPath := range findLibraries(pkgPath) {
-			libName := filepath.Base(libPath)
-			m.createLibSymlink(libPath, libName)
-			patchRpath(libPath, m.libDir)
-			libNames = append(libNames, libName)
-		}
-
-		for _, binPath := range findBinaries(pkgPath) {
-			binName := filepath.Base(binPath)
-			if err := m.createSymlink(binPath, binName); err != nil {
-				return nil, err
-			}
-			patchRpath(binPath, m.libDir)
-			binaryNames = append(binaryNames, binName)
-		}
+	binaryNames, libNames, appNames, err := m.extractAndLink(archivePath, pkgPath, newPackage.IsCask)
+	if err != nil {
+		return nil, err
 	}
 
 	installedPkg := &domain.InstalledPackage{
@@ -308,6 +371,7 @@ func (m *Manager) Upgrade(ctx context.Context, oldPackage domain.Package, newPac
 		Dependencies: oldDeps,
 		IsDep:        newPackage.IsDep,
 		IsCask:       newPackage.IsCask,
+		Pinned:       pinned,
 		InstalledAt:  pendingPkg.InstalledAt,
 	}
 
@@ -318,10 +382,63 @@ func (m *Manager) Upgrade(ctx context.Context, oldPackage domain.Package, newPac
 	return installedPkg, nil
 }
 
+// Reinstall re-extracts an installed package from its cached archive,
+// fetching it only if the cache no longer has it. Dependencies, pins
+// and the original install time are kept.
+func (m *Manager) Reinstall(ctx context.Context, pkg domain.Package) (_ *domain.InstalledPackage, err error) {
+	defer m.finish(pkg.Name, time.Now(), &err)
+
+	installed, installedPkg, _ := m.state.IsInstalled(pkg.Name)
+	if !installed {
+		return nil, fmt.Errorf("package %s is not installed", pkg.Name)
+	}
+
+	done := m.track(domain.PackageChange{
+		Name:         pkg.Name,
+		Action:       domain.ActionReinstall,
+		OldVersion:   installedPkg.FullVersion(),
+		NewVersion:   installedPkg.FullVersion(),
+		Dependencies: installedPkg.Dependencies,
+		IsDep:        installedPkg.IsDep,
+		IsCask:       installedPkg.IsCask,
+	})
+	defer func() { done(err) }()
+
+	archivePath, err := m.archive(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The package stays recorded as installed throughout. Extraction only
+	// replaces its directory once complete, so an interrupted reinstall
+	// at worst leaves it unlinked
+	m.unlink(installedPkg)
+
+	binaryNames, libNames, appNames, err := m.extractAndLink(archivePath, installedPkg.Path, installedPkg.IsCask)
+	if err != nil {
+		return nil, err
+	}
+
+	reinstalled := *installedPkg
+	reinstalled.Binaries = binaryNames
+	reinstalled.Libs = libNames
+	reinstalled.Apps = appNames
+
+	if err := m.state.Add(&reinstalled); err != nil {
+		return nil, err
+	}
+
+	return &reinstalled, nil
+}
+
 func (m *Manager) ListInstalled() (map[string]*domain.InstalledPackage, error) {
 	return m.state.ListInstalled()
 }
 
+func (m *Manager) IsInstalled(name string) (bool, *domain.InstalledPackage, error) {
+	return m.state.IsInstalled(name)
+}
+
 func (m *Manager) Reconcile() []string {
 	installed, err := m.state.ListInstalled()
 	if err != nil {
@@ -345,6 +462,31 @@ func (m *Manager) Reconcile() []string {
 	return removed
 }
 
+// Fetch downloads pkg into the cache without installing it. A cached
+// archive is reused unless force is set; cached reports whether it was.
+func (m *Manager) Fetch(ctx context.Context, pkg domain.Package, force bool) (path string, cached bool, err error) {
+	defer m.finish(pkg.Name, time.Now(), &err)
+
+	if !force && m.cache.Has(pkg.Name, pkg.FullVersion) {
+		return m.cache.GetPath(pkg.Name, pkg.FullVersion), true, nil
+	}
+
+	result := m.fetcher.Fetch(ctx, pkg)
+	if result.Error != nil {
+		return "", false, result.Error
+	}
+
+	path, err = m.cache.Store(pkg.Name, pkg.FullVersion, result.Path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to cache %s: %w", pkg.Name, err)
+	}
+	return path, false, nil
+}
+
+func (m *Manager) IsCached(name, version string) bool {
+	return m.cache.Has(name, version)
+}
+
 func (m *Manager) Flush() error {
 	return m.state.Flush()
 }
@@ -353,12 +495,191 @@ func (m *Manager) Clear(ctx context.Context) error {
 	return m.cache.Clear()
 }
 
-func (m *Manager) createSymlink(path, binName string) error {
-	if err := os.MkdirAll(m.binDir, 0755); err != nil {
+// archive returns the cached archive for pkg, downloading it first
+// if the cache does not have it yet.
+func (m *Manager) archive(ctx context.Context, pkg domain.Package) (string, error) {
+	if m.cache.Has(pkg.Name, pkg.FullVersion) {
+		return m.cache.GetPath(pkg.Name, pkg.FullVersion), nil
+	}
+
+	result := m.fetcher.Fetch(ctx, pkg)
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	archivePath, err := m.cache.Store(pkg.Name, pkg.FullVersion, result.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to cache %s: %w", pkg.Name, err)
+	}
+	return archivePath, nil
+}
+
+// extractAndLink unpacks an archive and links its binaries and libraries,
+// or copies its apps for casks.
+func (m *Manager) extractAndLink(archivePath, pkgPath string, isCask bool) (binaries, libs, apps []string, err error) {
+	name := filepath.Base(filepath.Dir(pkgPath))
+
+	if isCask {
+		size := archiveSize(archivePath)
+		started := time.Now()
+		m.emit(domain.Event{Package: name, Phase: domain.PhaseExtract, Total: size})
+		apps, err = m.extractor.ExtractApps(archivePath, m.appsDir)
+		m.emit(domain.Event{Package: name, Phase: domain.PhaseExtract, Total: size, Duration: time.Since(started), Done: true, Err: err})
+		return nil, nil, apps, err
+	}
+
+	if err := m.extract(archivePath, pkgPath); err != nil {
+		return nil, nil, nil, err
+	}
+
+	started := time.Now()
+	m.emit(domain.Event{Package: name, Phase: domain.PhaseLink})
+	defer func() {
+		m.emit(domain.Event{Package: name, Phase: domain.PhaseLink, Duration: time.Since(started), Done: true, Err: err})
+	}()
+
+	binaries, libs, err = m.link(pkgPath)
+	return binaries, libs, nil, err
+}
+
+// link links the binaries and libraries of an extracted package into the
+// bin and lib directories.
+func (m *Manager) link(pkgPath string) (binaries, libs []string, err error) {
+	for _, libPath := range findLibraries(pkgPath) {
+		libName := filepath.Base(libPath)
+		m.createLibSymlink(libPath, libName)
+		patchRpath(libPath, m.libDir)
+		libs = append(libs, libName)
+	}
+
+	for _, binPath := range findBinaries(pkgPath) {
+		binName := filepath.Base(binPath)
+		if err := m.createSymlink(m.binDir, binPath, binName); err != nil {
+			return nil, nil, err
+		}
+		patchRpath(binPath, m.libDir)
+		binaries = append(binaries, binName)
+	}
+
+	return binaries, libs, nil
+}
+
+// extract unpacks the archive of a formula into pkgPath.
+func (m *Manager) extract(archivePath, pkgPath string) error {
+	name := filepath.Base(filepath.Dir(pkgPath))
+	size := archiveSize(archivePath)
+
+	started := time.Now()
+	m.emit(domain.Event{Package: name, Phase: domain.PhaseExtract, Total: size})
+
+	err := m.extractStaged(archivePath, pkgPath)
+	m.emit(domain.Event{Package: name, Phase: domain.PhaseExtract, Total: size, Duration: time.Since(started), Done: true, Err: err})
+	return err
+}
+
+// extractStaged unpacks an archive into a staging directory inside the
+// packages directory and renames the package into pkgPath once it is
+// complete, replacing what was there. An interrupted extraction never
+// leaves a partial package at pkgPath.
+func (m *Manager) extractStaged(archivePath, pkgPath string) error {
+	if err := os.MkdirAll(m.packagesDir, 0755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(m.packagesDir, stagingPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := m.extractor.Extract(archivePath, staging); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(m.packagesDir, pkgPath)
+	if err != nil {
+		return err
+	}
+	extracted := filepath.Join(staging, rel)
+	if _, err := os.Stat(extracted); err != nil {
+		return fmt.Errorf("archive does not contain %s", rel)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pkgPath), 0755); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(pkgPath); err != nil {
+		return err
+	}
+	return os.Rename(extracted, pkgPath)
+}
+
+func archiveSize(path string) int64 {
+	if info, err := os.Stat(path); err == nil {
+		return info.Size()
+	}
+	return 0
+}
+
+// removePackageDir removes one version of a package from the packages
+// directory, and the package directory once no version is left. A
+// version that a project toolchain uses is kept.
+func (m *Manager) removePackageDir(name, version string) error {
+	if used, err := m.usedByProject(name, version); err != nil || used {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(m.packagesDir, name, version)); err != nil {
+		return err
+	}
+	// Fails while other versions are left, which is fine
+	os.Remove(filepath.Join(m.packagesDir, name))
+	return nil
+}
+
+// emit sends e to the event sink, if there is one.
+func (m *Manager) emit(e domain.Event) {
+	if m.events == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	m.events.Emit(e)
+}
+
+// finish reports the end of an operation on a package. It is deferred
+// with a pointer to the named error result so failures are included.
+func (m *Manager) finish(name string, started time.Time, err *error) {
+	m.emit(domain.Event{Package: name, Phase: domain.PhaseFinish, Duration: time.Since(started), Done: true, Err: *err})
+}
+
+// unlink removes the binary and library symlinks of a package, or its
+// apps for casks.
+func (m *Manager) unlink(pkg *domain.InstalledPackage) error {
+	if pkg.IsCask {
+		for _, appName := range pkg.Apps {
+			appPath := filepath.Join(m.appsDir, appName)
+			if err := os.RemoveAll(appPath); err != nil {
+				return fmt.Errorf("failed to remove app %s: %w", appName, err)
+			}
+		}
+		return nil
+	}
+
+	for _, binName := range pkg.Binaries {
+		os.Remove(filepath.Join(m.binDir, binName))
+	}
+	for _, libName := range pkg.Libs {
+		os.Remove(filepath.Join(m.libDir, libName))
+	}
+	return nil
+}
+
+func (m *Manager) createSymlink(binDir, path, binName string) error {
+	if err := os.MkdirAll(binDir, 0755); err != nil {
 		return fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
-	linkPath := filepath.Join(m.binDir, binName)
+	linkPath := filepath.Join(binDir, binName)
 
 	if _, err := os.Lstat(linkPath); err == nil {
 		os.Remove(linkPath)
@@ -378,16 +699,18 @@ func (m *Manager) createLibSymlink(src, libName string) {
 	os.Symlink(src, linkPath)
 }
 
-func patchRpath(path, libDir string) {
+// patchRpath points the library search path of a binary or library at
+// libDirs, searched in order.
+func patchRpath(path string, libDirs ...string) {
 	switch runtime.GOOS {
 	case "darwin":
-		patchDarwin(path, libDir)
+		patchDarwin(path, libDirs)
 	case "linux":
-		patchLinux(path, libDir)
+		patchLinux(path, libDirs)
 	}
 }
 
-func patchLinux(path, libDir string) {
+func patchLinux(path string, libDirs []string) {
 	if _, err := exec.LookPath("patchelf"); err != nil {
 		fmt.Fprintln(os.Stderr, "warning: patchelf not found, binaries may not work (install with: apt install patchelf / dnf install patchelf)")
 		return
@@ -396,7 +719,7 @@ func patchLinux(path, libDir string) {
 	if interp != "" {
 		exec.Command("patchelf", "--set-interpreter", interp, path).Run()
 	}
-	exec.Command("patchelf", "--set-rpath", libDir, path).Run()
+	exec.Command("patchelf", "--set-rpath", strings.Join(libDirs, ":"), path).Run()
 }
 
 func findSystemInterpreter() string {
@@ -409,7 +732,7 @@ func findSystemInterpreter() string {
 	return ""
 }
 
-func patchDarwin(path, libDir string) {
+func patchDarwin(path string, libDirs []string) {
 	out, err := exec.Command("otool", "-L", path).Output()
 	if err != nil {
 		return
@@ -434,7 +757,9 @@ func patchDarwin(path, libDir string) {
 		exec.Command("install_name_tool", "-change", libRef, newRef, path).Run()
 	}
 
-	exec.Command("install_name_tool", "-add_rpath", libDir, path).Run()
+	for _, libDir := range libDirs {
+		exec.Command("install_name_tool", "-add_rpath", libDir, path).Run()
+	}
 	exec.Command("codesign", "--force", "--sign", "-", path).Run()
 }
 
@@ -450,14 +775,17 @@ func findLibraries(dir string) []string {
 		if e.IsDir() {
 			continue
 		}
-		name := e.Name()
-		if strings.HasSuffix(name, ".dylib") || strings.HasSuffix(name, ".so") || strings.Contains(name, ".so.") {
-			libs = append(libs, filepath.Join(libDir, name))
+		if isLibrary(e.Name()) {
+			libs = append(libs, filepath.Join(libDir, e.Name()))
 		}
 	}
 	return libs
 }
 
+func isLibrary(name string) bool {
+	return strings.HasSuffix(name, ".dylib") || strings.HasSuffix(name, ".so") || strings.Contains(name, ".so.")
+}
+
 func findBinaries(dir string) []string {
 	candidates := []string{
 		filepath.Join(dir, "bin"),
@@ -486,18 +814,13 @@ func findExecutablesIn(binPath string) []string {
 			continue
 		}
 
-		name := e.Name()
-		if runtime.GOOS == "windows" && !strings.HasSuffix(strings.ToLower(name), ".exe") {
-			continue
-		}
-
-		fullPath := filepath.Join(binPath, name)
+		fullPath := filepath.Join(binPath, e.Name())
 		info, err := os.Stat(fullPath)
 		if err != nil {
 			continue
 		}
 
-		if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		if !isExecutable(e.Name(), info.Mode()) {
 			continue
 		}
 
@@ -506,3 +829,12 @@ func findExecutablesIn(binPath string) []string {
 
 	return executables
 }
+
+// isExecutable reports whether a file called name with mode is linked
+// into the bin directory.
+func isExecutable(name string, mode os.FileMode) bool {
+	if runtime.GOOS == "windows" {
+		return strings.HasSuffix(strings.ToLower(name), ".exe")
+	}
+	return mode&0111 != 0
+}
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+	"github.com/teamcutter/chatr/internal/state"
+)
+
+// newTestManager returns a manager whose state holds pkgs. Only the
+// state is set up, enough for the methods that read and plan.
+func newTestManager(t *testing.T, pkgs ...*domain.InstalledPackage) *Manager {
+	t.Helper()
+
+	dir := t.TempDir()
+	st, err := state.NewSQLite(filepath.Join(dir, "state.db"), filepath.Join(dir, "installed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	for _, pkg := range pkgs {
+		if pkg.Version == "" {
+			pkg.Version = "1.0"
+		}
+		if pkg.Path == "" {
+			pkg.Path = filepath.Join(dir, "packages", pkg.Name, pkg.FullVersion())
+		}
+		if err := st.Add(pkg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return New(nil, nil, nil, st, nil,
+		filepath.Join(dir, "packages"), filepath.Join(dir, "bin"), filepath.Join(dir, "lib"), filepath.Join(dir, "apps"))
+}
+
+func TestOrphans(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []*domain.InstalledPackage
+		want      []string
+	}{
+		{
+			name: "nothing installed",
+		},
+		{
+			name: "dependency of a root",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq", Dependencies: []string{"oniguruma"}},
+				{Name: "oniguruma", IsDep: true},
+			},
+		},
+		{
+			name: "root nothing depends on",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq"},
+			},
+		},
+		{
+			name: "dependency left behind",
+			installed: []*domain.InstalledPackage{
+				{Name: "jq"},
+				{Name: "oniguruma", IsDep: true},
+			},
+			want: []string{"oniguruma"},
+		},
+		{
+			name: "orphan keeps its own dependencies orphaned",
+			installed: []*domain.InstalledPackage{
+				{Name: "libgit2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "openssl", IsDep: true},
+			},
+			want: []string{"libgit2", "openssl"},
+		},
+		{
+			name: "dependency reachable through another dependency",
+			installed: []*domain.InstalledPackage{
+				{Name: "git", Dependencies: []string{"libgit2"}},
+				{Name: "libgit2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "openssl", IsDep: true},
+			},
+		},
+		{
+			name: "shared dependency still used by one root",
+			installed: []*domain.InstalledPackage{
+				{Name: "curl", Dependencies: []string{"openssl"}},
+				{Name: "libgit2", IsDep: true, Dependencies: []string{"openssl"}},
+				{Name: "openssl", IsDep: true},
+			},
+			want: []string{"libgit2"},
+		},
+		{
+			name: "dependency cycle",
+			installed: []*domain.InstalledPackage{
+				{Name: "a", IsDep: true, Dependencies: []string{"b"}},
+				{Name: "b", IsDep: true, Dependencies: []string{"a"}},
+			},
+			want: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.installed...)
+
+			orphans, err := m.Orphans()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, pkg := range orphans {
+				got = append(got, pkg.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Orphans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+// Versions returns every installed version of a package, oldest install
+// first. The active one has Active set.
+func (m *Manager) Versions(name string) ([]*domain.InstalledPackage, error) {
+	return m.state.Versions(name)
+}
+
+// Switch links another installed version of a package into the bin and
+// lib directories in place of the active one. Whether the package is a
+// dependency and its pin carry over to the new version.
+func (m *Manager) Switch(name, version string) (_ *domain.InstalledPackage, err error) {
+	current, target, err := m.findVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsCask {
+		return nil, fmt.Errorf("cannot switch versions of cask %s", name)
+	}
+	if target == current {
+		return target, nil
+	}
+
+	done := m.track(domain.PackageChange{
+		Name:         name,
+		Action:       domain.ActionSwitch,
+		OldVersion:   current.FullVersion(),
+		NewVersion:   target.FullVersion(),
+		OldURL:       current.URL,
+		Dependencies: current.Dependencies,
+		IsDep:        current.IsDep,
+	})
+	defer func() { done(err) }()
+
+	if err := m.unlink(current); err != nil {
+		return nil, err
+	}
+
+	target.Binaries, target.Libs, err = m.link(target.Path)
+	if err != nil {
+		return nil, err
+	}
+	target.IsDep = current.IsDep
+	target.Pinned = current.Pinned
+	target.Active = true
+
+	if err := m.state.Add(target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// removeVersion removes one version of a package that is not the active
+// one.
+func (m *Manager) removeVersion(pkg *domain.InstalledPackage) (err error) {
+	done := m.track(domain.PackageChange{
+		Name:         pkg.Name,
+		Action:       domain.ActionRemove,
+		OldVersion:   pkg.FullVersion(),
+		OldURL:       pkg.URL,
+		Dependencies: pkg.Dependencies,
+		IsDep:        pkg.IsDep,
+		Inactive:     true,
+	})
+	defer func() { done(err) }()
+
+	if err := m.removePackageDir(pkg.Name, pkg.FullVersion()); err != nil {
+		return err
+	}
+	return m.state.RemoveVersion(pkg.Name, pkg.FullVersion())
+}
+
+// restoreVersion installs pkg from its cached archive next to the active
+// version without linking it, which undoes removeVersion.
+func (m *Manager) restoreVersion(pkg domain.Package, deps []string) (err error) {
+	done := m.track(domain.PackageChange{
+		Name:       pkg.Name,
+		Action:     domain.ActionInstall,
+		NewVersion: pkg.FullVersion,
+		IsDep:      pkg.IsDep,
+		Inactive:   true,
+	})
+	defer func() { done(err) }()
+
+	if !m.cache.Has(pkg.Name, pkg.FullVersion) {
+		return fmt.Errorf("%s-%s is not in the cache", pkg.Name, pkg.FullVersion)
+	}
+
+	pkgPath := filepath.Join(m.packagesDir, pkg.Name, pkg.FullVersion)
+	if err := m.extract(m.cache.GetPath(pkg.Name, pkg.FullVersion), pkgPath); err != nil {
+		return err
+	}
+
+	restored := &domain.InstalledPackage{
+		Name:         pkg.Name,
+		Version:      pkg.Version,
+		Revision:     pkg.Revision,
+		URL:          pkg.DownloadURL,
+		Path:         pkgPath,
+		Dependencies: deps,
+		IsDep:        pkg.IsDep,
+		InstalledAt:  time.Now(),
+	}
+	for _, libPath := range findLibraries(pkgPath) {
+		restored.Libs = append(restored.Libs, filepath.Base(libPath))
+	}
+	for _, binPath := range findBinaries(pkgPath) {
+		restored.Binaries = append(restored.Binaries, filepath.Base(binPath))
+	}
+
+	return m.state.AddVersion(restored)
+}
+
+// findVersion returns the active version of a package and the installed
+// version matching version, as MatchVersion finds it.
+func (m *Manager) findVersion(name, version string) (active, match *domain.InstalledPackage, err error) {
+	versions, err := m.state.Versions(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(versions) == 0 {
+		return nil, nil, fmt.Errorf("package %s is not installed", name)
+	}
+
+	match, err = MatchVersion(name, version, versions)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range versions {
+		if v.Active {
+			active = v
+		}
+	}
+	// Nothing is linked if an interrupted command left no version active
+	if active == nil {
+		active = &domain.InstalledPackage{Name: name}
+	}
+	return active, match, nil
+}
+
+// MatchVersion returns the version among the installed versions of name
+// that version names. A full version matches exactly, a version without
+// its revision only if a single installed version has it.
+func MatchVersion(name, version string, versions []*domain.InstalledPackage) (*domain.InstalledPackage, error) {
+	var matches []*domain.InstalledPackage
+	for _, v := range versions {
+		if v.FullVersion() == version {
+			return v, nil
+		}
+		if v.Version == version {
+			matches = append(matches, v)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%s %s is not installed", name, version)
+	case 1:
+		return matches[0], nil
+	}
+	full := make([]string, len(matches))
+	for i, v := range matches {
+		full[i] = v.FullVersion()
+	}
+	return nil, fmt.Errorf("%s %s is ambiguous, use the full version: %s", name, version, strings.Join(full, ", "))
+}
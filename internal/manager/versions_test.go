@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []*domain.InstalledPackage
+		version   string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "full version",
+			installed: []*domain.InstalledPackage{{Version: "1.6"}, {Version: "1.7", Revision: "1"}},
+			version:   "1.7_1",
+			want:      "1.7_1",
+		},
+		{
+			name:      "version without its revision",
+			installed: []*domain.InstalledPackage{{Version: "1.6"}, {Version: "1.7", Revision: "1"}},
+			version:   "1.7",
+			want:      "1.7_1",
+		},
+		{
+			name:      "exact match before revisions",
+			installed: []*domain.InstalledPackage{{Version: "1.7", Revision: "1"}, {Version: "1.7"}},
+			version:   "1.7",
+			want:      "1.7",
+		},
+		{
+			name:      "several revisions are ambiguous",
+			installed: []*domain.InstalledPackage{{Version: "1.7", Revision: "1"}, {Version: "1.7", Revision: "2"}},
+			version:   "1.7",
+			wantErr:   true,
+		},
+		{
+			name:      "not installed",
+			installed: []*domain.InstalledPackage{{Version: "1.7", Revision: "1"}},
+			version:   "1.7_2",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchVersion("jq", tt.version, tt.installed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchVersion(%s) error = %v, want error %v", tt.version, err, tt.wantErr)
+			}
+			if err == nil && got.FullVersion() != tt.want {
+				t.Errorf("MatchVersion(%s) = %s, want %s", tt.version, got.FullVersion(), tt.want)
+			}
+		})
+	}
+}
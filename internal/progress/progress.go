@@ -0,0 +1,143 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+const (
+	ModeBar   = "bar"
+	ModePlain = "plain"
+	ModeJSON  = "json"
+)
+
+// New returns the sink for a --progress mode writing to w.
+func New(mode string, w io.Writer) (domain.EventSink, error) {
+	switch mode {
+	case ModeBar, "":
+		return &BarSink{bars: make(map[string]*progressbar.ProgressBar)}, nil
+	case ModePlain:
+		return &PlainSink{w: w, reported: make(map[string]int64)}, nil
+	case ModeJSON:
+		return &JSONSink{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode %q (expected bar, plain or json)", mode)
+	}
+}
+
+// BarSink draws a download progress bar per package and ignores the
+// other phases, which interactive commands summarize themselves.
+type BarSink struct {
+	mu   sync.Mutex
+	bars map[string]*progressbar.ProgressBar
+}
+
+func (s *BarSink) Emit(e domain.Event) {
+	if e.Phase != domain.PhaseDownload {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bar, ok := s.bars[e.Package]
+	if !ok {
+		total := e.Total
+		if total <= 0 {
+			total = -1
+		}
+		bar = progressbar.DefaultBytes(total, fmt.Sprintf("Downloading %s", e.Package))
+		s.bars[e.Package] = bar
+	}
+	bar.Set64(e.Bytes)
+
+	if e.Done {
+		delete(s.bars, e.Package)
+	}
+}
+
+// PlainSink writes one line of text per event. Download progress is
+// reported in 25% steps to keep logs short.
+type PlainSink struct {
+	mu       sync.Mutex
+	w        io.Writer
+	reported map[string]int64
+}
+
+func (s *PlainSink) Emit(e domain.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := fmt.Sprintf("%s %s %s", e.Time.Format(time.TimeOnly), e.Phase, e.Package)
+
+	switch {
+	case e.Err != nil:
+		line += fmt.Sprintf(" failed after %s: %v", e.Duration.Round(time.Millisecond), e.Err)
+	case e.Done:
+		if e.Phase == domain.PhaseDownload {
+			line += fmt.Sprintf(" %d bytes", e.Bytes)
+		}
+		line += fmt.Sprintf(" done in %s", e.Duration.Round(time.Millisecond))
+		delete(s.reported, e.Package)
+	case e.Phase == domain.PhaseDownload:
+		if e.Total <= 0 {
+			return
+		}
+		step := e.Bytes * 4 / e.Total
+		if last, ok := s.reported[e.Package]; ok && step <= last {
+			return
+		}
+		s.reported[e.Package] = step
+		line += fmt.Sprintf(" %d/%d bytes (%d%%)", e.Bytes, e.Total, e.Bytes*100/e.Total)
+	default:
+		line += " started"
+	}
+
+	fmt.Fprintln(s.w, line)
+}
+
+// JSONSink writes every event as a line of JSON. Durations are only
+// set on the Done event that ends a phase.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+type jsonEvent struct {
+	Time       time.Time `json:"time"`
+	Package    string    `json:"package"`
+	Phase      string    `json:"phase"`
+	Bytes      int64     `json:"bytes,omitempty"`
+	Total      int64     `json:"total,omitempty"`
+	DurationMS *float64  `json:"duration_ms,omitempty"`
+	Done       bool      `json:"done"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (s *JSONSink) Emit(e domain.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := jsonEvent{
+		Time:    e.Time,
+		Package: e.Package,
+		Phase:   e.Phase,
+		Bytes:   e.Bytes,
+		Total:   e.Total,
+		Done:    e.Done,
+	}
+	if e.Done {
+		ms := float64(e.Duration.Microseconds()) / 1000
+		out.DurationMS = &ms
+	}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	s.enc.Encode(out)
+}
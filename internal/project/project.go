@@ -0,0 +1,89 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/teamcutter/chatr/internal/tomlfile"
+)
+
+// FileName is the project file looked up from the current directory
+// upwards.
+const FileName = ".chatr.toml"
+
+// Latest asks for whatever version the registry has.
+const Latest = "latest"
+
+// ErrNotFound is returned by Find when no directory up to the root has
+// a project file.
+var ErrNotFound = errors.New("no " + FileName + " found")
+
+// Project declares the tools a directory tree needs and their versions.
+type Project struct {
+	// Root is the directory containing the project file
+	Root  string            `toml:"-"`
+	Tools map[string]string `toml:"tools"`
+}
+
+// Find loads the project file in dir or the closest of its parents.
+func Find(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return Load(path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotFound
+		}
+		dir = parent
+	}
+}
+
+// Load reads and validates the project file at path.
+func Load(path string) (*Project, error) {
+	var p Project
+	if err := tomlfile.Decode(path, &p); err != nil {
+		return nil, err
+	}
+
+	for name, version := range p.Tools {
+		if version == "" {
+			p.Tools[name] = Latest
+		}
+	}
+
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	p.Root = root
+	return &p, nil
+}
+
+// Names returns the tool names in sorted order.
+func (p *Project) Names() []string {
+	names := make([]string, 0, len(p.Tools))
+	for name := range p.Tools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Dir is the directory holding the project's symlink farm.
+func (p *Project) Dir() string {
+	return filepath.Join(p.Root, ".chatr")
+}
+
+// BinDir is the directory put first on PATH inside the project.
+func (p *Project) BinDir() string {
+	return filepath.Join(p.Dir(), "bin")
+}
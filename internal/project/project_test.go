@@ -0,0 +1,145 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			content: "",
+		},
+		{
+			name:    "versions",
+			content: "[tools]\nterraform = \"1.5.7\"\nprotoc = \"latest\"\n",
+			want:    map[string]string{"terraform": "1.5.7", "protoc": Latest},
+		},
+		{
+			name:    "empty version is latest",
+			content: "[tools]\njq = \"\"\n",
+			want:    map[string]string{"jq": Latest},
+		},
+		{
+			name:    "unknown key",
+			content: "[tools]\njq = \"1.7\"\n\n[tool]\nfd = \"9.0\"\n",
+			wantErr: "unknown keys: tool, tool.fd",
+		},
+		{
+			name:    "version that is not a string",
+			content: "[tools]\njq = 1.7\n",
+			wantErr: "incompatible types",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.Tools, tt.want) {
+				t.Errorf("Load() tools = %v, want %v", p.Tools, tt.want)
+			}
+			if p.Root != dir {
+				t.Errorf("Load() root = %q, want %q", p.Root, dir)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are the directories, relative to a temporary root, that
+		// hold a project file
+		files []string
+		dir   string
+		// want is the expected project root, relative to the temporary
+		// root, empty for ErrNotFound
+		want string
+	}{
+		{
+			name:  "in the directory",
+			files: []string{"app"},
+			dir:   "app",
+			want:  "app",
+		},
+		{
+			name:  "in a parent",
+			files: []string{"app"},
+			dir:   "app/src/cmd",
+			want:  "app",
+		},
+		{
+			name:  "closest wins",
+			files: []string{"app", "app/tools"},
+			dir:   "app/tools/gen",
+			want:  "app/tools",
+		},
+		{
+			name:  "not in a sibling",
+			files: []string{"other"},
+			dir:   "app/src",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, f := range tt.files {
+				dir := filepath.Join(root, f)
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, FileName), []byte("[tools]\njq = \"1.7\"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := filepath.Join(root, tt.dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := Find(dir)
+			if tt.want == "" {
+				// A project file above the temporary root would be found
+				if !errors.Is(err, ErrNotFound) && (err != nil || strings.HasPrefix(p.Root, root)) {
+					t.Fatalf("Find() = %+v, %v, want ErrNotFound", p, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(root, tt.want); p.Root != want {
+				t.Errorf("Find() root = %q, want %q", p.Root, want)
+			}
+			if p.BinDir() != filepath.Join(root, tt.want, ".chatr", "bin") {
+				t.Errorf("BinDir() = %q", p.BinDir())
+			}
+		})
+	}
+}
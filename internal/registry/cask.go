@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -117,7 +118,7 @@ func (c *CaskRegistry) Get(ctx context.Context, name string) (*domain.Formula, e
 		return toFormulaCask(cask), nil
 	}
 
-	return nil, fmt.Errorf("cask %q not found", name)
+	return nil, fmt.Errorf("cask %q %w", name, ErrNotFound)
 }
 
 func (c *CaskRegistry) Search(ctx context.Context, query string) ([]domain.Formula, error) {
@@ -141,6 +142,49 @@ func (c *CaskRegistry) GetVersion(ctx context.Context, name string) (string, err
 	return formula.Version, nil
 }
 
+// Refresh loads the index, downloading it again if the cached copy has expired.
+func (c *CaskRegistry) Refresh(ctx context.Context) error {
+	return c.loadIndex(ctx)
+}
+
+// IndexUpdatedAt reports when the cached index was last written to disk.
+func (c *CaskRegistry) IndexUpdatedAt() (time.Time, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	info, err := os.Stat(filepath.Join(c.formulaeDir, "casks.json"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// CachedNames returns the names in the index cached on disk, however old
+// it is. It never downloads the index and returns nil if there is none.
+func (c *CaskRegistry) CachedNames() []string {
+	data, ok := c.getFromCached(time.Duration(math.MaxInt64))
+	if !ok {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil
+	}
+
+	var names []string
+	for dec.More() {
+		var entry struct {
+			Token string `json:"token"`
+		}
+		if err := dec.Decode(&entry); err != nil {
+			return names
+		}
+		names = append(names, entry.Token)
+	}
+	return names
+}
+
 func (c *CaskRegistry) getFromCached(ttl time.Duration) ([]byte, bool) {
 	c.RLock()
 	defer c.RUnlock()
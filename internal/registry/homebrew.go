@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -21,6 +23,9 @@ import (
 
 const baseUrl string = "https://formulae.brew.sh/api/"
 
+// ErrNotFound is returned by Get when the index has no such package.
+var ErrNotFound = errors.New("not found")
+
 type HomebrewRegistry struct {
 	sync.RWMutex
 	client      *http.Client
@@ -137,7 +142,7 @@ func (h *HomebrewRegistry) Get(ctx context.Context, name string) (*domain.Formul
 		return h.toFormula(f), nil
 	}
 
-	return nil, fmt.Errorf("formula %q not found", name)
+	return nil, fmt.Errorf("formula %q %w", name, ErrNotFound)
 }
 
 func (h *HomebrewRegistry) Search(ctx context.Context, query string) ([]domain.Formula, error) {
@@ -195,6 +200,49 @@ func (h *HomebrewRegistry) filterAndSort(formulae []Formulae, query string) []do
 	return results
 }
 
+// Refresh loads the index, downloading it again if the cached copy has expired.
+func (h *HomebrewRegistry) Refresh(ctx context.Context) error {
+	return h.loadIndex(ctx)
+}
+
+// IndexUpdatedAt reports when the cached index was last written to disk.
+func (h *HomebrewRegistry) IndexUpdatedAt() (time.Time, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	info, err := os.Stat(filepath.Join(h.formulaeDir, "formulae.json"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// CachedNames returns the names in the index cached on disk, however old
+// it is. It never downloads the index and returns nil if there is none.
+func (h *HomebrewRegistry) CachedNames() []string {
+	data, ok := h.getFromCached(time.Duration(math.MaxInt64))
+	if !ok {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil
+	}
+
+	var names []string
+	for dec.More() {
+		var entry struct {
+			Name string `json:"name"`
+		}
+		if err := dec.Decode(&entry); err != nil {
+			return names
+		}
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
 func (h *HomebrewRegistry) getFromCached(ttl time.Duration) ([]byte, bool) {
 	h.RLock()
 	defer h.RUnlock()
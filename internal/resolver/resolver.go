@@ -28,10 +28,8 @@ func New(registry domain.Registry, state domain.State) *Resolver {
 }
 
 func (r *Resolver) Resolve(ctx context.Context, name string) ([]ResolvedPackage, error) {
-	var mu sync.Mutex
-	fetched := make(map[string]*domain.Formula)
-
-	if err := r.fetchAll(ctx, name, fetched, &mu); err != nil {
+	fetched, err := r.Graph(ctx, name)
+	if err != nil {
 		return nil, err
 	}
 
@@ -42,6 +40,18 @@ func (r *Resolver) Resolve(ctx context.Context, name string) ([]ResolvedPackage,
 	return result, nil
 }
 
+// Graph returns every formula reachable from name, keyed by formula name.
+func (r *Resolver) Graph(ctx context.Context, name string) (map[string]*domain.Formula, error) {
+	var mu sync.Mutex
+	fetched := make(map[string]*domain.Formula)
+
+	if err := r.fetchAll(ctx, name, fetched, &mu); err != nil {
+		return nil, err
+	}
+
+	return fetched, nil
+}
+
 func (r *Resolver) fetchAll(ctx context.Context, name string, fetched map[string]*domain.Formula, mu *sync.Mutex) error {
 	mu.Lock()
 	if _, exists := fetched[name]; exists {
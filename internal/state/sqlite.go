@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -14,11 +16,15 @@ import (
 	"github.com/teamcutter/chatr/internal/domain"
 )
 
+// Every installed version of a package has its own row, keyed by its
+// full version as domain.FormatVersion returns it. The active one is
+// linked into the bin and lib directories.
 const schema = `
 CREATE TABLE IF NOT EXISTS packages (
-    name         TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
     version      TEXT NOT NULL,
-    revision     TEXT DEFAULT '',
+    revision     TEXT NOT NULL DEFAULT '',
+    full_version TEXT NOT NULL,
     url          TEXT NOT NULL,
     path         TEXT NOT NULL,
     binaries     TEXT NOT NULL DEFAULT '[]',
@@ -28,15 +34,47 @@ CREATE TABLE IF NOT EXISTS packages (
     is_dep       INTEGER NOT NULL DEFAULT 0,
     is_cask      INTEGER NOT NULL DEFAULT 0,
     installed_at TEXT NOT NULL,
-    status       TEXT NOT NULL DEFAULT 'installed'
+    status       TEXT NOT NULL DEFAULT 'installed',
+    pinned       INTEGER NOT NULL DEFAULT 0,
+    active       INTEGER NOT NULL DEFAULT 1,
+    PRIMARY KEY (name, full_version)
+);
+
+CREATE TABLE IF NOT EXISTS project_packages (
+    project      TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    full_version TEXT NOT NULL,
+    PRIMARY KEY (project, name, full_version)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at TEXT NOT NULL,
+    command    TEXT NOT NULL,
+    packages   TEXT NOT NULL DEFAULT '[]',
+    outcome    TEXT NOT NULL DEFAULT 'pending'
 );
 `
 
+// tables lists the tables created by schema. Tables added after the
+// initial schema are created in existing databases on open.
+var tables = []string{"packages", "project_packages", "transactions"}
+
+// addedColumns lists columns introduced after the initial schema.
+// They are added to existing databases on open.
+var addedColumns = []struct {
+	name       string
+	definition string
+}{
+	{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+}
+
 type SQLiteState struct {
 	mu           sync.RWMutex
 	db           *sql.DB
 	dbPath       string
 	manifestPath string
+	recovered    []string
 }
 
 func NewSQLite(dbPath, manifestPath string) (*SQLiteState, error) {
@@ -49,17 +87,17 @@ func NewSQLite(dbPath, manifestPath string) (*SQLiteState, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if _, err := db.Exec(schema); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create schema: %w", err)
-	}
-
 	s := &SQLiteState{
 		db:           db,
 		dbPath:       dbPath,
 		manifestPath: manifestPath,
 	}
 
+	if err := s.migrateSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	if err := s.migrate(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to migrate: %w", err)
@@ -73,6 +111,186 @@ func NewSQLite(dbPath, manifestPath string) (*SQLiteState, error) {
 	return s, nil
 }
 
+// OpenReadOnly opens the state only to look at it. Unlike NewSQLite it
+// does not clean up after interrupted installs, which may still be
+// running in another process. A database written by an older chatr is
+// brought up to the current schema first. A missing database reads as
+// empty.
+func OpenReadOnly(dbPath string) (*SQLiteState, error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		// Every connection would get its own empty database
+		db.SetMaxOpenConns(1)
+		if _, err := db.Exec(schema); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create schema: %w", err)
+		}
+		return &SQLiteState{db: db, dbPath: dbPath}, nil
+	}
+
+	s, err := openFile(dbPath, "mode=ro")
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.upToDate()
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if current {
+		return s, nil
+	}
+	s.Close()
+
+	// Migrating needs a writable handle, but only the first time a
+	// newer chatr opens the database
+	w, err := openFile(dbPath, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := w.migrateSchema(); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return openFile(dbPath, "mode=ro")
+}
+
+// openFile opens the database at dbPath with the given URI query.
+func openFile(dbPath, query string) (*SQLiteState, error) {
+	// Paths can contain ? and #, which would end the file name
+	dsn := url.URL{Scheme: "file", Path: dbPath, RawQuery: query}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &SQLiteState{db: db, dbPath: dbPath}, nil
+}
+
+// migrateSchema creates missing tables and brings the packages table of
+// an existing database up to date.
+func (s *SQLiteState) migrateSchema() error {
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	if err := s.addColumns(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	if err := s.rekey(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return nil
+}
+
+// upToDate reports whether the database has every table and column
+// that migrateSchema would add.
+func (s *SQLiteState) upToDate() (bool, error) {
+	for _, table := range tables {
+		var n int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+			table).Scan(&n); err != nil {
+			return false, err
+		}
+		if n == 0 {
+			return false, nil
+		}
+	}
+
+	columns, err := s.columns()
+	if err != nil {
+		return false, err
+	}
+	for _, col := range addedColumns {
+		if !columns[col.name] {
+			return false, nil
+		}
+	}
+	return columns["full_version"], nil
+}
+
+func (s *SQLiteState) addColumns() error {
+	existing, err := s.columns()
+	if err != nil {
+		return err
+	}
+
+	for _, col := range addedColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE packages ADD COLUMN %s %s", col.name, col.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// rekey rebuilds a packages table from before side by side versions,
+// which was keyed by name alone, into one keyed by name and full
+// version. Every row becomes the active version of its package, and a
+// revision of "0" is stored as "" the way insertPkg does.
+func (s *SQLiteState) rekey() error {
+	columns, err := s.columns()
+	if err != nil {
+		return err
+	}
+	if columns["full_version"] {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		"ALTER TABLE packages RENAME TO packages_old",
+		schema,
+		`INSERT INTO packages (name, version, revision, full_version, url, path,
+		       binaries, libs, apps, dependencies, is_dep, is_cask, installed_at, status, pinned, active)
+		SELECT name, version, r, version || CASE WHEN r = '' THEN '' ELSE '_' || r END, url, path,
+		       binaries, libs, apps, dependencies, is_dep, is_cask, installed_at, status, pinned, 1
+		FROM (SELECT *, CASE WHEN COALESCE(revision, '') IN ('', '0') THEN '' ELSE revision END AS r
+		      FROM packages_old)`,
+		"DROP TABLE packages_old",
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// columns returns the names of the columns of the packages table.
+func (s *SQLiteState) columns() (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info('packages')")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
 func (s *SQLiteState) migrate() error {
 	var count int
 	if err := s.db.QueryRow("SELECT COUNT(*) FROM packages").Scan(&count); err != nil {
@@ -103,7 +321,7 @@ func (s *SQLiteState) migrate() error {
 	defer tx.Rollback()
 
 	for _, pkg := range manifest.Packages {
-		if err := s.insertPkg(tx, pkg, "installed"); err != nil {
+		if err := s.insertPkg(tx, pkg, "installed", true); err != nil {
 			return fmt.Errorf("failed to insert %s: %w", pkg.Name, err)
 		}
 	}
@@ -161,15 +379,109 @@ func (s *SQLiteState) recover() error {
 			os.RemoveAll(p.path)
 		}
 
-		if _, err := s.db.Exec("DELETE FROM packages WHERE name = ?", p.name); err != nil {
+		if _, err := s.db.Exec("DELETE FROM packages WHERE name = ? AND status = 'pending'", p.name); err != nil {
 			return fmt.Errorf("failed to delete pending package %s: %w", p.name, err)
 		}
+		s.recovered = append(s.recovered, p.name)
+	}
+
+	return s.recoverTransactions()
+}
+
+// recoverTransactions marks transactions left pending by an interrupted
+// command, and the changes that were in flight, as recovered. Packages
+// cleaned up by recover that no transaction mentions are added to the
+// most recent one.
+func (s *SQLiteState) recoverTransactions() error {
+	pending, err := s.queryTransactions("WHERE outcome = ?", domain.OutcomePending)
+	if err != nil {
+		return err
+	}
+
+	unrecorded := slices.Clone(s.recovered)
+	for _, t := range pending {
+		for i := range t.Changes {
+			if t.Changes[i].Outcome == domain.OutcomePending {
+				t.Changes[i].Outcome = domain.OutcomeRecovered
+			}
+			unrecorded = slices.DeleteFunc(unrecorded, func(name string) bool {
+				return name == t.Changes[i].Name
+			})
+		}
+	}
+
+	for i, t := range pending {
+		if i == 0 {
+			for _, name := range unrecorded {
+				t.Changes = append(t.Changes, domain.PackageChange{
+					Name:    name,
+					Action:  domain.ActionInstall,
+					Outcome: domain.OutcomeRecovered,
+				})
+			}
+		}
+
+		if len(t.Changes) == 0 {
+			if _, err := s.db.Exec("DELETE FROM transactions WHERE id = ?", t.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		t.Outcome = domain.OutcomeRecovered
+		if err := s.updateTransaction(t); err != nil {
+			return fmt.Errorf("failed to recover transaction %d: %w", t.ID, err)
+		}
 	}
 
 	return nil
 }
 
-func (s *SQLiteState) insertPkg(tx *sql.Tx, pkg *domain.InstalledPackage, status string) error {
+// Recovered returns the packages whose interrupted installs were
+// cleaned up when the state was opened.
+func (s *SQLiteState) Recovered() []string {
+	return append([]string{}, s.recovered...)
+}
+
+// Pending returns the names of packages still marked as pending.
+func (s *SQLiteState) Pending() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT name FROM packages WHERE status = 'pending'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// insertPkg stores one version of a package. Making it the active one
+// deactivates the other versions.
+func (s *SQLiteState) insertPkg(tx *sql.Tx, pkg *domain.InstalledPackage, status string, active bool) error {
+	fullVersion := pkg.FullVersion()
+	if active {
+		if _, err := tx.Exec("UPDATE packages SET active = 0 WHERE name = ? AND full_version != ?",
+			pkg.Name, fullVersion); err != nil {
+			return err
+		}
+	}
+
+	// The registry reports no revision as "0"
+	revision := pkg.Revision
+	if revision == "0" {
+		revision = ""
+	}
+
 	binaries, _ := json.Marshal(pkg.Binaries)
 	libs, _ := json.Marshal(pkg.Libs)
 	apps, _ := json.Marshal(pkg.Apps)
@@ -177,12 +489,12 @@ func (s *SQLiteState) insertPkg(tx *sql.Tx, pkg *domain.InstalledPackage, status
 
 	_, err := tx.Exec(`
 		INSERT OR REPLACE INTO packages
-		(name, version, revision, url, path, binaries, libs, apps, dependencies, is_dep, is_cask, installed_at, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		pkg.Name, pkg.Version, pkg.Revision, pkg.URL, pkg.Path,
+		(name, version, revision, full_version, url, path, binaries, libs, apps, dependencies, is_dep, is_cask, installed_at, status, pinned, active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pkg.Name, pkg.Version, revision, fullVersion, pkg.URL, pkg.Path,
 		string(binaries), string(libs), string(apps), string(deps),
 		boolToInt(pkg.IsDep), boolToInt(pkg.IsCask),
-		pkg.InstalledAt.Format(time.RFC3339), status)
+		pkg.InstalledAt.Format(time.RFC3339), status, boolToInt(pkg.Pinned), boolToInt(active))
 	return err
 }
 
@@ -208,12 +520,13 @@ func (s *SQLiteState) Save(m *domain.Manifest) error {
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.Exec("DELETE FROM packages"); err != nil {
+	// The manifest only holds active versions, the others stay installed
+	if _, err := tx.Exec("DELETE FROM packages WHERE active = 1"); err != nil {
 		return err
 	}
 
 	for _, pkg := range m.Packages {
-		if err := s.insertPkg(tx, pkg, "installed"); err != nil {
+		if err := s.insertPkg(tx, pkg, "installed", true); err != nil {
 			return err
 		}
 	}
@@ -242,14 +555,14 @@ func (s *SQLiteState) IsInstalled(name string) (bool, *domain.InstalledPackage,
 func (s *SQLiteState) getPkg(name string) (*domain.InstalledPackage, error) {
 	var pkg domain.InstalledPackage
 	var binaries, libs, apps, deps, installedAt, status string
-	var isDep, isCask int
+	var isDep, isCask, pinned int
 
 	err := s.db.QueryRow(`
 		SELECT name, version, revision, url, path, binaries, libs, apps, dependencies,
-		       is_dep, is_cask, installed_at, status
-		FROM packages WHERE name = ? AND status = 'installed'`, name).Scan(
+		       is_dep, is_cask, installed_at, status, pinned
+		FROM packages WHERE name = ? AND status = 'installed' AND active = 1`, name).Scan(
 		&pkg.Name, &pkg.Version, &pkg.Revision, &pkg.URL, &pkg.Path,
-		&binaries, &libs, &apps, &deps, &isDep, &isCask, &installedAt, &status)
+		&binaries, &libs, &apps, &deps, &isDep, &isCask, &installedAt, &status, &pinned)
 	if err != nil {
 		return nil, err
 	}
@@ -260,6 +573,8 @@ func (s *SQLiteState) getPkg(name string) (*domain.InstalledPackage, error) {
 	json.Unmarshal([]byte(deps), &pkg.Dependencies)
 	pkg.IsDep = isDep == 1
 	pkg.IsCask = isCask == 1
+	pkg.Pinned = pinned == 1
+	pkg.Active = true
 	pkg.InstalledAt, _ = time.Parse(time.RFC3339, installedAt)
 
 	return &pkg, nil
@@ -275,13 +590,33 @@ func (s *SQLiteState) Add(pkg *domain.InstalledPackage) error {
 	}
 	defer tx.Rollback()
 
-	if err := s.insertPkg(tx, pkg, "installed"); err != nil {
+	if err := s.insertPkg(tx, pkg, "installed", true); err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
 
+// AddVersion stores an installed version of a package without making it
+// the active one.
+func (s *SQLiteState) AddVersion(pkg *domain.InstalledPackage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertPkg(tx, pkg, "installed", false); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Remove forgets every version of a package.
 func (s *SQLiteState) Remove(name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -290,6 +625,58 @@ func (s *SQLiteState) Remove(name string) error {
 	return err
 }
 
+// RemoveVersion forgets one version of a package.
+func (s *SQLiteState) RemoveVersion(name, fullVersion string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec("DELETE FROM packages WHERE name = ? AND full_version = ?", name, fullVersion)
+	return err
+}
+
+// Versions returns every installed version of a package, oldest install
+// first.
+func (s *SQLiteState) Versions(name string) ([]*domain.InstalledPackage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT name, version, revision, url, path, binaries, libs, apps, dependencies,
+		       is_dep, is_cask, installed_at, pinned, active
+		FROM packages WHERE name = ? AND status = 'installed'
+		ORDER BY installed_at`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*domain.InstalledPackage
+	for rows.Next() {
+		var pkg domain.InstalledPackage
+		var binaries, libs, apps, deps, installedAt string
+		var isDep, isCask, pinned, active int
+
+		if err := rows.Scan(&pkg.Name, &pkg.Version, &pkg.Revision, &pkg.URL, &pkg.Path,
+			&binaries, &libs, &apps, &deps, &isDep, &isCask, &installedAt, &pinned, &active); err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal([]byte(binaries), &pkg.Binaries)
+		json.Unmarshal([]byte(libs), &pkg.Libs)
+		json.Unmarshal([]byte(apps), &pkg.Apps)
+		json.Unmarshal([]byte(deps), &pkg.Dependencies)
+		pkg.IsDep = isDep == 1
+		pkg.IsCask = isCask == 1
+		pkg.Pinned = pinned == 1
+		pkg.Active = active == 1
+		pkg.InstalledAt, _ = time.Parse(time.RFC3339, installedAt)
+
+		versions = append(versions, &pkg)
+	}
+
+	return versions, rows.Err()
+}
+
 func (s *SQLiteState) Flush() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -305,8 +692,8 @@ func (s *SQLiteState) ListInstalled() (map[string]*domain.InstalledPackage, erro
 func (s *SQLiteState) listInstalled() (map[string]*domain.InstalledPackage, error) {
 	rows, err := s.db.Query(`
 		SELECT name, version, revision, url, path, binaries, libs, apps, dependencies,
-		       is_dep, is_cask, installed_at
-		FROM packages WHERE status = 'installed'`)
+		       is_dep, is_cask, installed_at, pinned
+		FROM packages WHERE status = 'installed' AND active = 1`)
 	if err != nil {
 		return nil, err
 	}
@@ -316,10 +703,10 @@ func (s *SQLiteState) listInstalled() (map[string]*domain.InstalledPackage, erro
 	for rows.Next() {
 		var pkg domain.InstalledPackage
 		var binaries, libs, apps, deps, installedAt string
-		var isDep, isCask int
+		var isDep, isCask, pinned int
 
 		if err := rows.Scan(&pkg.Name, &pkg.Version, &pkg.Revision, &pkg.URL, &pkg.Path,
-			&binaries, &libs, &apps, &deps, &isDep, &isCask, &installedAt); err != nil {
+			&binaries, &libs, &apps, &deps, &isDep, &isCask, &installedAt, &pinned); err != nil {
 			return nil, err
 		}
 
@@ -329,6 +716,8 @@ func (s *SQLiteState) listInstalled() (map[string]*domain.InstalledPackage, erro
 		json.Unmarshal([]byte(deps), &pkg.Dependencies)
 		pkg.IsDep = isDep == 1
 		pkg.IsCask = isCask == 1
+		pkg.Pinned = pinned == 1
+		pkg.Active = true
 		pkg.InstalledAt, _ = time.Parse(time.RFC3339, installedAt)
 
 		pkgs[pkg.Name] = &pkg
@@ -347,13 +736,133 @@ func (s *SQLiteState) BeginInstall(pkg *domain.InstalledPackage) error {
 	}
 	defer tx.Rollback()
 
-	if err := s.insertPkg(tx, pkg, "pending"); err != nil {
+	if err := s.insertPkg(tx, pkg, "pending", false); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetProjectPackages replaces the package versions recorded for project.
+func (s *SQLiteState) SetProjectPackages(project string, pkgs []domain.ProjectPackage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM project_packages WHERE project = ?", project); err != nil {
 		return err
 	}
+	for _, p := range pkgs {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO project_packages (project, name, full_version)
+			VALUES (?, ?, ?)`, project, p.Name, p.FullVersion); err != nil {
+			return err
+		}
+	}
 
 	return tx.Commit()
 }
 
+// ProjectPackages returns the package versions recorded for every
+// project.
+func (s *SQLiteState) ProjectPackages() ([]domain.ProjectPackage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT project, name, full_version FROM project_packages ORDER BY project, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pkgs []domain.ProjectPackage
+	for rows.Next() {
+		var p domain.ProjectPackage
+		if err := rows.Scan(&p.Project, &p.Name, &p.FullVersion); err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, rows.Err()
+}
+
+func (s *SQLiteState) BeginTransaction(t *domain.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes, _ := json.Marshal(t.Changes)
+	res, err := s.db.Exec(`
+		INSERT INTO transactions (started_at, command, packages, outcome)
+		VALUES (?, ?, ?, ?)`,
+		t.Time.Format(time.RFC3339), t.Command, string(changes), t.Outcome)
+	if err != nil {
+		return err
+	}
+
+	t.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *SQLiteState) UpdateTransaction(t *domain.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updateTransaction(t)
+}
+
+func (s *SQLiteState) updateTransaction(t *domain.Transaction) error {
+	changes, _ := json.Marshal(t.Changes)
+	_, err := s.db.Exec("UPDATE transactions SET packages = ?, outcome = ? WHERE id = ?",
+		string(changes), t.Outcome, t.ID)
+	return err
+}
+
+func (s *SQLiteState) DeleteTransaction(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec("DELETE FROM transactions WHERE id = ?", id)
+	return err
+}
+
+// Transactions returns every recorded transaction, newest first.
+func (s *SQLiteState) Transactions() ([]*domain.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queryTransactions("")
+}
+
+func (s *SQLiteState) queryTransactions(where string, args ...any) ([]*domain.Transaction, error) {
+	rows, err := s.db.Query(`
+		SELECT id, started_at, command, packages, outcome
+		FROM transactions `+where+` ORDER BY id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*domain.Transaction
+	for rows.Next() {
+		var t domain.Transaction
+		var startedAt, changes string
+
+		if err := rows.Scan(&t.ID, &startedAt, &t.Command, &changes, &t.Outcome); err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal([]byte(changes), &t.Changes)
+		t.Time, _ = time.Parse(time.RFC3339, startedAt)
+
+		transactions = append(transactions, &t)
+	}
+
+	return transactions, rows.Err()
+}
+
 func (s *SQLiteState) exportJSON() error {
 	pkgs, err := s.listInstalled()
 	if err != nil {
@@ -0,0 +1,372 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamcutter/chatr/internal/domain"
+)
+
+func newTestState(t *testing.T) *SQLiteState {
+	t.Helper()
+
+	dir := t.TempDir()
+	st, err := NewSQLite(filepath.Join(dir, "state.db"), filepath.Join(dir, "installed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// writeDB creates a database at path by running stmts, the way an older
+// chatr would have left it.
+func writeDB(t *testing.T, path string, stmts ...string) {
+	t.Helper()
+
+	dsn := url.URL{Scheme: "file", Path: path}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+// rows returns the stored packages as "name full_version revision",
+// with " (inactive)" appended to versions that are not active.
+func rows(t *testing.T, st *SQLiteState) []string {
+	t.Helper()
+
+	r, err := st.db.Query("SELECT name, full_version, revision, active FROM packages ORDER BY name, full_version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var got []string
+	for r.Next() {
+		var name, fullVersion, revision string
+		var active int
+		if err := r.Scan(&name, &fullVersion, &revision, &active); err != nil {
+			t.Fatal(err)
+		}
+		s := fmt.Sprintf("%s %s %q", name, fullVersion, revision)
+		if active == 0 {
+			s += " (inactive)"
+		}
+		got = append(got, s)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestAddVersions(t *testing.T) {
+	type add struct {
+		version  string
+		revision string
+		// inactive versions are added with AddVersion
+		inactive bool
+	}
+
+	tests := []struct {
+		name string
+		adds []add
+		want []string
+	}{
+		{
+			name: "revision 0 is stored as none",
+			adds: []add{{version: "1.7", revision: "0"}},
+			want: []string{`jq 1.7 ""`},
+		},
+		{
+			name: "both spellings of a version are one row",
+			adds: []add{{version: "1.7", revision: "0"}, {version: "1.7"}},
+			want: []string{`jq 1.7 ""`},
+		},
+		{
+			name: "revisions are separate versions",
+			adds: []add{{version: "1.7"}, {version: "1.7", revision: "1"}},
+			want: []string{`jq 1.7 "" (inactive)`, `jq 1.7_1 "1"`},
+		},
+		{
+			name: "adding deactivates the other versions",
+			adds: []add{{version: "1.6"}, {version: "1.7"}, {version: "1.8"}},
+			want: []string{`jq 1.6 "" (inactive)`, `jq 1.7 "" (inactive)`, `jq 1.8 ""`},
+		},
+		{
+			name: "adding an old version activates it again",
+			adds: []add{{version: "1.6"}, {version: "1.7"}, {version: "1.6"}},
+			want: []string{`jq 1.6 ""`, `jq 1.7 "" (inactive)`},
+		},
+		{
+			name: "AddVersion keeps the active version",
+			adds: []add{{version: "1.7"}, {version: "1.6", inactive: true}},
+			want: []string{`jq 1.6 "" (inactive)`, `jq 1.7 ""`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestState(t)
+			for i, a := range tt.adds {
+				pkg := &domain.InstalledPackage{
+					Name:        "jq",
+					Version:     a.version,
+					Revision:    a.revision,
+					InstalledAt: time.Date(2024, 3, 1+i, 0, 0, 0, 0, time.UTC),
+				}
+				add := st.Add
+				if a.inactive {
+					add = st.AddVersion
+				}
+				if err := add(pkg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := rows(t, st); !slices.Equal(got, tt.want) {
+				t.Errorf("packages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	st := newTestState(t)
+	for i, v := range []string{"1.6", "1.7"} {
+		pkg := &domain.InstalledPackage{
+			Name:        "jq",
+			Version:     v,
+			Revision:    "1",
+			InstalledAt: time.Date(2024, 3, 1+i, 0, 0, 0, 0, time.UTC),
+		}
+		if err := st.Add(pkg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := st.Versions("jq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, fmt.Sprintf("%s %v", v.FullVersion(), v.Active))
+	}
+	if want := []string{"1.6_1 false", "1.7_1 true"}; !slices.Equal(got, want) {
+		t.Errorf("Versions(jq) = %q, want %q", got, want)
+	}
+
+	if err := st.RemoveVersion("jq", "1.6_1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows(t, st), []string{`jq 1.7_1 "1"`}; !slices.Equal(got, want) {
+		t.Errorf("packages after RemoveVersion = %q, want %q", got, want)
+	}
+
+	installed, pkg, err := st.IsInstalled("jq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !installed || pkg.FullVersion() != "1.7_1" {
+		t.Errorf("IsInstalled(jq) = %v, %+v, want 1.7_1", installed, pkg)
+	}
+}
+
+// oldColumns are the columns every packages table has had.
+const oldColumns = `
+    name         TEXT NOT NULL,
+    version      TEXT NOT NULL,
+    revision     TEXT,
+    url          TEXT NOT NULL,
+    path         TEXT NOT NULL,
+    binaries     TEXT NOT NULL DEFAULT '[]',
+    libs         TEXT NOT NULL DEFAULT '[]',
+    apps         TEXT NOT NULL DEFAULT '[]',
+    dependencies TEXT NOT NULL DEFAULT '[]',
+    is_dep       INTEGER NOT NULL DEFAULT 0,
+    is_cask      INTEGER NOT NULL DEFAULT 0,
+    installed_at TEXT NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'installed'`
+
+func TestRekey(t *testing.T) {
+	tests := []struct {
+		name  string
+		stmts []string
+		want  []string
+	}{
+		{
+			name: "keyed by name",
+			stmts: []string{
+				"CREATE TABLE packages (" + oldColumns + ", PRIMARY KEY (name))",
+				`INSERT INTO packages (name, version, revision, url, path, installed_at) VALUES
+				 ('jq', '1.7', '0', 'u', 'p', '2024-03-01T00:00:00Z'),
+				 ('fd', '9.0', '1', 'u', 'p', '2024-03-01T00:00:00Z'),
+				 ('rg', '14.0', NULL, 'u', 'p', '2024-03-01T00:00:00Z')`,
+			},
+			want: []string{`fd 9.0_1 "1"`, `jq 1.7 ""`, `rg 14.0 ""`},
+		},
+		{
+			name: "keyed by name with pins",
+			stmts: []string{
+				"CREATE TABLE packages (" + oldColumns + `,
+				    pinned INTEGER NOT NULL DEFAULT 0,
+				    PRIMARY KEY (name))`,
+				`INSERT INTO packages (name, version, revision, url, path, installed_at, pinned) VALUES
+				 ('jq', '1.7', '', 'u', 'p', '2024-03-02T00:00:00Z', 1),
+				 ('fd', '9.0', '2', 'u', 'p', '2024-03-01T00:00:00Z', 0)`,
+			},
+			want: []string{`fd 9.0_2 "2"`, `jq 1.7 ""`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "state.db")
+			writeDB(t, dbPath, tt.stmts...)
+
+			st, err := NewSQLite(dbPath, filepath.Join(dir, "installed.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer st.Close()
+
+			if got := rows(t, st); !slices.Equal(got, tt.want) {
+				t.Errorf("packages = %q, want %q", got, tt.want)
+			}
+			if _, pkg, err := st.IsInstalled("jq"); err != nil || pkg == nil || pkg.Version != "1.7" {
+				t.Errorf("IsInstalled(jq) = %+v, %v, want 1.7", pkg, err)
+			}
+		})
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	current := []string{
+		schema,
+		`INSERT INTO packages (name, version, full_version, url, path, installed_at)
+		 VALUES ('jq', '1.7', '1.7', 'u', 'p', '2024-03-01T00:00:00Z')`,
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		stmts   []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "missing database",
+		},
+		{
+			name:  "current schema",
+			stmts: current,
+			want:  []string{"jq"},
+		},
+		{
+			name:  "path with URI characters",
+			file:  "state?mode=rw#1.db",
+			stmts: current,
+			want:  []string{"jq"},
+		},
+		{
+			name: "before pinned",
+			stmts: []string{
+				"CREATE TABLE packages (" + oldColumns + ", PRIMARY KEY (name))",
+				`INSERT INTO packages (name, version, revision, url, path, installed_at)
+				 VALUES ('jq', '1.7', '', 'u', 'p', '2024-03-01T00:00:00Z')`,
+			},
+			want: []string{"jq"},
+		},
+		{
+			name: "before side by side versions",
+			stmts: []string{
+				"CREATE TABLE packages (" + oldColumns + `,
+				    pinned INTEGER NOT NULL DEFAULT 0,
+				    PRIMARY KEY (name))`,
+				`INSERT INTO packages (name, version, revision, url, path, installed_at, pinned) VALUES
+				 ('jq', '1.7', '1', 'u', 'p', '2024-03-01T00:00:00Z', 1),
+				 ('fd', '9.0', '0', 'u', 'p', '2024-03-01T00:00:00Z', 0)`,
+			},
+			want: []string{"fd", "jq"},
+		},
+		{
+			name: "before projects",
+			stmts: []string{
+				strings.Replace(schema, "CREATE TABLE IF NOT EXISTS project_packages", "CREATE TABLE IF NOT EXISTS unused", 1),
+				`INSERT INTO packages (name, version, full_version, url, path, installed_at)
+				 VALUES ('jq', '1.7', '1.7', 'u', 'p', '2024-03-01T00:00:00Z')`,
+			},
+			want: []string{"jq"},
+		},
+		{
+			name:    "not a chatr database",
+			stmts:   []string{"CREATE TABLE packages (name TEXT)"},
+			wantErr: "failed to migrate schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tt.file
+			if file == "" {
+				file = "state.db"
+			}
+			dbPath := filepath.Join(t.TempDir(), file)
+			if tt.stmts != nil {
+				writeDB(t, dbPath, tt.stmts...)
+			}
+
+			st, err := OpenReadOnly(dbPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("OpenReadOnly() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer st.Close()
+
+			installed, err := st.ListInstalled()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for name := range installed {
+				got = append(got, name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListInstalled() = %q, want %q", got, tt.want)
+			}
+
+			if _, err := st.ProjectPackages(); err != nil {
+				t.Errorf("ProjectPackages() error = %v", err)
+			}
+			if _, err := st.Transactions(); err != nil {
+				t.Errorf("Transactions() error = %v", err)
+			}
+
+			if err := st.Add(&domain.InstalledPackage{Name: "fd", Version: "9.0"}); err == nil && tt.stmts != nil {
+				t.Errorf("Add() wrote to a read-only database")
+			}
+		})
+	}
+}
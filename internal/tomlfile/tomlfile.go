@@ -0,0 +1,28 @@
+// Package tomlfile decodes the TOML files chatr reads, rejecting keys
+// they do not define.
+package tomlfile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Decode reads the TOML file at path into v. Keys that v has no field
+// for are an error, so that a misspelled key is not silently ignored.
+func Decode(path string, v any) error {
+	md, err := toml.DecodeFile(path, v)
+	if err != nil {
+		return err
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return fmt.Errorf("%s: unknown keys: %s", path, strings.Join(keys, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package tomlfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type file struct {
+	Name  string `toml:"name"`
+	Items []item `toml:"items"`
+}
+
+type item struct {
+	Name string `toml:"name"`
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    file
+		wantErr string
+	}{
+		{
+			name:    "known keys",
+			content: "name = \"a\"\n\n[[items]]\n  name = \"b\"\n",
+			want:    file{Name: "a", Items: []item{{Name: "b"}}},
+		},
+		{
+			name:    "unknown key",
+			content: "name = \"a\"\nnmae = \"b\"\n",
+			wantErr: "unknown keys: nmae",
+		},
+		{
+			name:    "unknown keys in a table array",
+			content: "[[items]]\n  name = \"b\"\n  version = \"1\"\n  url = \"u\"\n",
+			wantErr: "unknown keys: items.version, items.url",
+		},
+		{
+			name:    "not TOML",
+			content: "brew \"jq\"\n",
+			wantErr: "expected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.toml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var got file
+			err := Decode(path, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if strings.Contains(tt.wantErr, "unknown") && !strings.HasPrefix(err.Error(), path) {
+					t.Errorf("Decode() error = %v, want it to name %s", err, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if err := Decode(filepath.Join(t.TempDir(), "missing.toml"), &file{}); !os.IsNotExist(err) {
+		t.Errorf("Decode(missing) error = %v, want a not-exist error", err)
+	}
+}